@@ -0,0 +1,459 @@
+// Package anthropic implements a backend for the Anthropic Messages API.
+package anthropic
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"strings"
+
+	"github.com/phomola/ai-go/gemini/ai"
+	"github.com/phomola/ai-go/internal/backend"
+	"github.com/phomola/ai-go/internal/httpjson"
+	"google.golang.org/genai"
+)
+
+var _ ai.Backend = (*Backend)(nil)
+
+const (
+	// DefaultBaseURL is the base URL of the Anthropic API.
+	DefaultBaseURL = "https://api.anthropic.com/v1"
+	// APIVersion is the version of the API sent with each request.
+	APIVersion = "2023-06-01"
+	// DefaultMaxTokens is the maximum number of output tokens if the config doesn't specify it.
+	DefaultMaxTokens = 8192
+)
+
+// responseTool is the name of the tool used for structured output.
+const responseTool = "structured_response"
+
+// Backend is a backend for the Messages API.
+type Backend struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// NewBackend creates a new backend.
+func NewBackend(apiKey string) *Backend {
+	return &Backend{APIKey: apiKey}
+}
+
+// NewClient creates a new client with a Messages API backend.
+func NewClient(apiKey string, model ai.Model, opts ...ai.Option) *ai.Client {
+	return ai.NewClientWithBackend(NewBackend(apiKey), model, opts...)
+}
+
+type (
+	request struct {
+		Model         string      `json:"model"`
+		MaxTokens     int32       `json:"max_tokens"`
+		System        string      `json:"system,omitempty"`
+		Messages      []*message  `json:"messages"`
+		Tools         []*tool     `json:"tools,omitempty"`
+		ToolChoice    *toolChoice `json:"tool_choice,omitempty"`
+		Temperature   *float32    `json:"temperature,omitempty"`
+		TopP          *float32    `json:"top_p,omitempty"`
+		StopSequences []string    `json:"stop_sequences,omitempty"`
+		Thinking      *thinking   `json:"thinking,omitempty"`
+		Stream        bool        `json:"stream,omitempty"`
+	}
+
+	thinking struct {
+		Type         string `json:"type"`
+		BudgetTokens int32  `json:"budget_tokens"`
+	}
+
+	message struct {
+		Role    string   `json:"role"`
+		Content []*block `json:"content"`
+	}
+
+	block struct {
+		Type      string          `json:"type"`
+		Text      string          `json:"text,omitempty"`
+		Source    *source         `json:"source,omitempty"`
+		ID        string          `json:"id,omitempty"`
+		Name      string          `json:"name,omitempty"`
+		Input     json.RawMessage `json:"input,omitempty"`
+		ToolUseID string          `json:"tool_use_id,omitempty"`
+		Content   string          `json:"content,omitempty"`
+		IsError   bool            `json:"is_error,omitempty"`
+		Thinking  string          `json:"thinking,omitempty"`
+		Signature string          `json:"signature,omitempty"`
+	}
+
+	source struct {
+		Type      string `json:"type"`
+		MediaType string `json:"media_type,omitempty"`
+		Data      string `json:"data,omitempty"`
+		URL       string `json:"url,omitempty"`
+	}
+
+	tool struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		InputSchema any    `json:"input_schema"`
+	}
+
+	toolChoice struct {
+		Type string `json:"type"`
+		Name string `json:"name,omitempty"`
+	}
+
+	response struct {
+		Content    []*block `json:"content"`
+		StopReason string   `json:"stop_reason"`
+		Usage      *usage   `json:"usage"`
+	}
+
+	usage struct {
+		InputTokens          int32 `json:"input_tokens"`
+		OutputTokens         int32 `json:"output_tokens"`
+		CacheReadInputTokens int32 `json:"cache_read_input_tokens"`
+	}
+
+	event struct {
+		Type         string    `json:"type"`
+		Index        int       `json:"index"`
+		Message      *response `json:"message"`
+		ContentBlock *block    `json:"content_block"`
+		Delta        *delta    `json:"delta"`
+		Usage        *usage    `json:"usage"`
+		Error        *apiError `json:"error"`
+	}
+
+	delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		Thinking    string `json:"thinking"`
+		Signature   string `json:"signature"`
+		StopReason  string `json:"stop_reason"`
+	}
+
+	apiError struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	}
+)
+
+// GenerateContent generates a response.
+func (b *Backend) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	req, names, err := newRequest(model, contents, config)
+	if err != nil {
+		return nil, err
+	}
+	var resp response
+	if err := httpjson.Call(ctx, b.HTTPClient, b.url(), b.header(), req, &resp); err != nil {
+		return nil, err
+	}
+	return newResponse(resp.Content, resp.StopReason, resp.Usage, names)
+}
+
+// GenerateContentStream generates a streamed response.
+func (b *Backend) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		req, names, err := newRequest(model, contents, config)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		req.Stream = true
+		body, err := httpjson.Post(ctx, b.HTTPClient, b.url(), b.header(), req)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer body.Close()
+		var (
+			blocks     []*block
+			inputs     []*strings.Builder
+			stopReason string
+			u          = new(usage)
+		)
+		for data, err := range httpjson.Events(body) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			var ev event
+			if err := json.Unmarshal(data, &ev); err != nil {
+				yield(nil, err)
+				return
+			}
+			switch ev.Type {
+			case "error":
+				yield(nil, fmt.Errorf("%s: %s", ev.Error.Type, ev.Error.Message))
+				return
+			case "message_start":
+				if ev.Message != nil && ev.Message.Usage != nil {
+					u = ev.Message.Usage
+				}
+			case "content_block_start":
+				for len(blocks) <= ev.Index {
+					blocks = append(blocks, nil)
+					inputs = append(inputs, new(strings.Builder))
+				}
+				blocks[ev.Index] = ev.ContentBlock
+			case "content_block_delta":
+				if ev.Index >= len(blocks) || ev.Delta == nil {
+					continue
+				}
+				bl := blocks[ev.Index]
+				switch ev.Delta.Type {
+				case "text_delta":
+					bl.Text += ev.Delta.Text
+					if bl.Type == "text" {
+						content := genai.NewContentFromText(ev.Delta.Text, genai.RoleModel)
+						if !yield(&genai.GenerateContentResponse{Candidates: []*genai.Candidate{{Content: content}}}, nil) {
+							return
+						}
+					}
+				case "input_json_delta":
+					inputs[ev.Index].WriteString(ev.Delta.PartialJSON)
+				case "thinking_delta":
+					bl.Thinking += ev.Delta.Thinking
+				case "signature_delta":
+					bl.Signature += ev.Delta.Signature
+				}
+			case "content_block_stop":
+				// Text blocks have already been streamed. The other blocks are yielded
+				// once they're complete, so the parts stay in the order of the blocks.
+				if ev.Index >= len(blocks) || blocks[ev.Index] == nil || blocks[ev.Index].Type == "text" {
+					continue
+				}
+				bl := blocks[ev.Index]
+				if bl.Type == "tool_use" {
+					bl.Input = json.RawMessage(inputs[ev.Index].String())
+				}
+				resp, err := newResponse([]*block{bl}, "", nil, names)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				if !yield(resp, nil) {
+					return
+				}
+			case "message_delta":
+				if ev.Delta != nil && ev.Delta.StopReason != "" {
+					stopReason = ev.Delta.StopReason
+				}
+				if ev.Usage != nil {
+					u.OutputTokens = ev.Usage.OutputTokens
+				}
+			}
+		}
+		resp, err := newResponse(nil, stopReason, u, names)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		yield(resp, nil)
+	}
+}
+
+func (b *Backend) url() string {
+	baseURL := b.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/messages"
+}
+
+func (b *Backend) header() http.Header {
+	h := make(http.Header)
+	h.Set("x-api-key", b.APIKey)
+	h.Set("anthropic-version", APIVersion)
+	return h
+}
+
+// toolName replaces characters not allowed in tool names, such as the colons in the names produced by infer.Functions.
+func toolName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func newRequest(model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*request, map[string]string, error) {
+	if config == nil {
+		config = new(genai.GenerateContentConfig)
+	}
+	req := &request{
+		Model:         model,
+		MaxTokens:     config.MaxOutputTokens,
+		System:        backend.Text(config.SystemInstruction),
+		Temperature:   config.Temperature,
+		TopP:          config.TopP,
+		StopSequences: config.StopSequences,
+	}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = DefaultMaxTokens
+	}
+	if tc := config.ThinkingConfig; tc != nil && tc.ThinkingBudget != nil && *tc.ThinkingBudget > 0 {
+		req.Thinking = &thinking{Type: "enabled", BudgetTokens: *tc.ThinkingBudget}
+	}
+	names := make(map[string]string)
+	for _, t := range config.Tools {
+		for _, decl := range t.FunctionDeclarations {
+			name := toolName(decl.Name)
+			names[name] = decl.Name
+			req.Tools = append(req.Tools, &tool{
+				Name:        name,
+				Description: decl.Description,
+				InputSchema: backend.Parameters(decl),
+			})
+		}
+	}
+	if config.ResponseMIMEType == "application/json" {
+		var schema any = map[string]any{"type": "object"}
+		switch {
+		case config.ResponseJsonSchema != nil:
+			schema = config.ResponseJsonSchema
+		case config.ResponseSchema != nil:
+			schema = config.ResponseSchema
+		}
+		req.Tools = append(req.Tools, &tool{
+			Name:        responseTool,
+			Description: "Returns the final response. Always use this tool to respond.",
+			InputSchema: schema,
+		})
+		switch {
+		case req.Thinking != nil:
+			// A forced tool choice isn't allowed with extended thinking.
+			req.ToolChoice = &toolChoice{Type: "auto"}
+			req.System = strings.TrimSpace(req.System + "\n\nWhen you have the final response, return it by calling the " + responseTool + " tool.")
+		case len(req.Tools) == 1:
+			req.ToolChoice = &toolChoice{Type: "tool", Name: responseTool}
+		default:
+			req.ToolChoice = &toolChoice{Type: "any"}
+		}
+	}
+	for _, c := range contents {
+		msg, err := toMessage(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(msg.Content) > 0 {
+			req.Messages = append(req.Messages, msg)
+		}
+	}
+	return req, names, nil
+}
+
+func toMessage(c *genai.Content) (*message, error) {
+	msg := &message{Role: "user"}
+	if c.Role == genai.RoleModel {
+		msg.Role = "assistant"
+	}
+	for _, p := range c.Parts {
+		switch {
+		case p.Thought:
+			if p.ThoughtSignature != nil {
+				msg.Content = append(msg.Content, &block{Type: "thinking", Thinking: p.Text, Signature: string(p.ThoughtSignature)})
+			}
+		case p.FunctionCall != nil:
+			input, err := json.Marshal(p.FunctionCall.Args)
+			if err != nil {
+				return nil, err
+			}
+			if p.FunctionCall.Args == nil {
+				input = []byte("{}")
+			}
+			msg.Content = append(msg.Content, &block{Type: "tool_use", ID: backend.CallID(p.FunctionCall.ID, toolName(p.FunctionCall.Name)), Name: toolName(p.FunctionCall.Name), Input: input})
+		case p.FunctionResponse != nil:
+			out, err := json.Marshal(p.FunctionResponse.Response)
+			if err != nil {
+				return nil, err
+			}
+			_, isError := p.FunctionResponse.Response["error"]
+			msg.Content = append(msg.Content, &block{Type: "tool_result", ToolUseID: backend.CallID(p.FunctionResponse.ID, toolName(p.FunctionResponse.Name)), Content: string(out), IsError: isError})
+		case p.InlineData != nil:
+			bl, err := dataBlock(p.InlineData.MIMEType, &source{Type: "base64", MediaType: p.InlineData.MIMEType, Data: base64.StdEncoding.EncodeToString(p.InlineData.Data)})
+			if err != nil {
+				return nil, err
+			}
+			msg.Content = append(msg.Content, bl)
+		case p.FileData != nil:
+			bl, err := dataBlock(p.FileData.MIMEType, &source{Type: "url", URL: p.FileData.FileURI})
+			if err != nil {
+				return nil, err
+			}
+			msg.Content = append(msg.Content, bl)
+		case p.Text != "":
+			msg.Content = append(msg.Content, &block{Type: "text", Text: p.Text})
+		}
+	}
+	return msg, nil
+}
+
+func dataBlock(mimeType string, src *source) (*block, error) {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return &block{Type: "image", Source: src}, nil
+	case mimeType == ai.MimeTypePDF:
+		return &block{Type: "document", Source: src}, nil
+	default:
+		return nil, fmt.Errorf("MIME type '%s' not supported", mimeType)
+	}
+}
+
+func newResponse(blocks []*block, stopReason string, u *usage, names map[string]string) (*genai.GenerateContentResponse, error) {
+	var parts []*genai.Part
+	for _, bl := range blocks {
+		switch bl.Type {
+		case "text":
+			parts = append(parts, genai.NewPartFromText(bl.Text))
+		case "thinking":
+			parts = append(parts, &genai.Part{Text: bl.Thinking, Thought: true, ThoughtSignature: []byte(bl.Signature)})
+		case "tool_use":
+			if bl.Name == responseTool {
+				parts = append(parts, genai.NewPartFromText(string(bl.Input)))
+				continue
+			}
+			var args map[string]any
+			if len(bl.Input) > 0 {
+				if err := json.Unmarshal(bl.Input, &args); err != nil {
+					return nil, fmt.Errorf("input of tool call '%s' ill-formed: %w", bl.Name, err)
+				}
+			}
+			name := bl.Name
+			if n, ok := names[name]; ok {
+				name = n
+			}
+			part := genai.NewPartFromFunctionCall(name, args)
+			part.FunctionCall.ID = bl.ID
+			parts = append(parts, part)
+		}
+	}
+	resp := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content:      genai.NewContentFromParts(parts, genai.RoleModel),
+			FinishReason: backend.FinishReason(stopReason, stopReasons),
+		}},
+	}
+	if u != nil {
+		resp.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:        u.InputTokens,
+			CandidatesTokenCount:    u.OutputTokens,
+			CachedContentTokenCount: u.CacheReadInputTokens,
+			TotalTokenCount:         u.InputTokens + u.OutputTokens,
+		}
+	}
+	return resp, nil
+}
+
+var stopReasons = map[string]genai.FinishReason{
+	"end_turn":      genai.FinishReasonStop,
+	"stop_sequence": genai.FinishReasonStop,
+	"tool_use":      genai.FinishReasonStop,
+	"pause_turn":    genai.FinishReasonStop,
+	"max_tokens":    genai.FinishReasonMaxTokens,
+	"refusal":       genai.FinishReasonSafety,
+}
@@ -0,0 +1,265 @@
+package anthropic
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/phomola/ai-go/gemini/ai"
+	"github.com/phomola/ai-go/infer"
+	"github.com/phomola/ai-go/internal/stub"
+	"github.com/stretchr/testify/require"
+)
+
+func newStubServer(t *testing.T, responses ...string) *stub.Server {
+	return stub.NewServer(t, "/v1/messages", http.Header{"x-api-key": {"key"}, "anthropic-version": {APIVersion}}, responses...)
+}
+
+func newTestClient(s *stub.Server) *ai.Client {
+	return ai.NewClientWithBackend(&Backend{BaseURL: s.URL + "/v1", APIKey: "key"}, "claude-test")
+}
+
+type customerService struct{}
+
+type customer struct {
+	Name string `json:"name"`
+}
+
+func (s *customerService) GetCustomer(_ context.Context, in *struct {
+	ID string `json:"id"`
+}, _ *struct {
+	Info any `guide:"Looks up a customer."`
+}) (*customer, error) {
+	return &customer{Name: strings.ToUpper(in.ID)}, nil
+}
+
+func TestToolCalling(t *testing.T) {
+	req := require.New(t)
+
+	s := newStubServer(t,
+		`{"content":[{"type":"text","text":"Let me check."},{"type":"tool_use","id":"toolu_1","name":"customerService_GetCustomer","input":{"id":"jane"}}],"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":5}}`,
+		`{"content":[{"type":"text","text":"The customer is JANE."}],"stop_reason":"end_turn","usage":{"input_tokens":20,"output_tokens":6}}`,
+	)
+	cl := newTestClient(s)
+	funcs, err := infer.Functions(new(customerService))
+	req.Nil(err)
+	tool, err := infer.Tool(funcs)
+	req.Nil(err)
+
+	resp, err := cl.GenerateText(context.Background(), ai.NewText("Who is jane?"), []*ai.Tool{tool})
+	req.Nil(err)
+	req.Equal("The customer is JANE.", resp.String())
+
+	tools := s.Requests()[0]["tools"].([]any)
+	req.Equal("customerService_GetCustomer", tools[0].(map[string]any)["name"])
+	req.Equal(float64(DefaultMaxTokens), s.Requests()[0]["max_tokens"])
+	msgs := s.Requests()[1]["messages"].([]any)
+	req.Equal(3, len(msgs))
+	use := msgs[1].(map[string]any)["content"].([]any)[1].(map[string]any)
+	req.Equal(map[string]any{"type": "tool_use", "id": "toolu_1", "name": "customerService_GetCustomer", "input": map[string]any{"id": "jane"}}, use)
+	result := msgs[2].(map[string]any)
+	req.Equal("user", result["role"])
+	req.Equal(map[string]any{"type": "tool_result", "tool_use_id": "toolu_1", "content": `{"output":{"name":"JANE"}}`}, result["content"].([]any)[0])
+}
+
+type cv struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func TestStructuredOutput(t *testing.T) {
+	req := require.New(t)
+
+	s := newStubServer(t,
+		`{"content":[{"type":"tool_use","id":"toolu_1","name":"structured_response","input":{"name":"Jane","email":"jane@example.com"}}],"stop_reason":"tool_use","usage":{"input_tokens":100,"output_tokens":20}}`,
+	)
+	cl := newTestClient(s)
+
+	out, err := cl.Generate[cv](context.Background(), ai.NewTextWithBytes("Extract the CV.", []byte("%PDF-1.4"), ai.MimeTypePDF), nil)
+	req.Nil(err)
+	req.Equal(&cv{Name: "Jane", Email: "jane@example.com"}, out)
+
+	req.Equal(map[string]any{"type": "tool", "name": "structured_response"}, s.Requests()[0]["tool_choice"])
+	content := s.Requests()[0]["messages"].([]any)[0].(map[string]any)["content"].([]any)
+	req.Equal(map[string]any{"type": "document", "source": map[string]any{"type": "base64", "media_type": "application/pdf", "data": "JVBERi0xLjQ="}}, content[0])
+	req.Equal(map[string]any{"type": "text", "text": "Extract the CV."}, content[1])
+}
+
+func TestStructuredOutputWithThinking(t *testing.T) {
+	req := require.New(t)
+
+	s := newStubServer(t,
+		`{"content":[{"type":"thinking","thinking":"The name is Jane.","signature":"sig"},{"type":"tool_use","id":"toolu_1","name":"structured_response","input":{"name":"Jane","email":"jane@example.com"}}],"stop_reason":"tool_use","usage":{"input_tokens":100,"output_tokens":40}}`,
+	)
+	cl := newTestClient(s)
+
+	out, err := cl.Generate[cv](context.Background(), ai.NewText("Jane, jane@example.com"), nil, ai.WithThinkingBudget(1024), ai.WithSystemInstruction("Extract the CV."))
+	req.Nil(err)
+	req.Equal(&cv{Name: "Jane", Email: "jane@example.com"}, out)
+
+	req.Equal(map[string]any{"type": "auto"}, s.Requests()[0]["tool_choice"])
+	req.Equal(map[string]any{"type": "enabled", "budget_tokens": float64(1024)}, s.Requests()[0]["thinking"])
+	req.True(strings.HasPrefix(s.Requests()[0]["system"].(string), "Extract the CV.\n\n"))
+	req.Contains(s.Requests()[0]["system"], "structured_response")
+}
+
+func TestStreaming(t *testing.T) {
+	req := require.New(t)
+
+	s := newStubServer(t, `event: message_start
+data: {"type":"message_start","message":{"content":[],"usage":{"input_tokens":8,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking. "}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"customerService_GetCustomer","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"id\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"jane\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}
+
+`, `event: message_start
+data: {"type":"message_start","message":{"content":[],"usage":{"input_tokens":12,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":", world."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":4}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`)
+	cl := newTestClient(s)
+	funcs, err := infer.Functions(new(customerService))
+	req.Nil(err)
+	tool, err := infer.Tool(funcs)
+	req.Nil(err)
+
+	var (
+		text  strings.Builder
+		resps int
+		total int32
+	)
+	for chunk, err := range cl.GenerateTextStream(context.Background(), ai.NewText("Say hello to jane."), []*ai.Tool{tool}) {
+		req.Nil(err)
+		text.WriteString(chunk.Text)
+		if chunk.FunctionResponse != nil {
+			resps++
+			req.Equal("customerService:GetCustomer", chunk.FunctionResponse.Name)
+			req.Equal(map[string]any{"output": map[string]any{"name": "JANE"}}, chunk.FunctionResponse.Response)
+		}
+		if chunk.Usage != nil {
+			total = chunk.Usage.TotalTokenCount
+		}
+	}
+	req.Equal("Checking. Hello, world.", text.String())
+	req.Equal(1, resps)
+	req.Equal(int32(17+16), total)
+	req.Equal(true, s.Requests()[0]["stream"])
+}
+
+func TestStreamingBlockOrder(t *testing.T) {
+	req := require.New(t)
+
+	s := newStubServer(t, `event: message_start
+data: {"type":"message_start","message":{"content":[],"usage":{"input_tokens":8,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Look her up."}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Checking. "}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: content_block_start
+data: {"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_1","name":"customerService_GetCustomer","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"id\":\"jane\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":2}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}
+
+`, `event: message_start
+data: {"type":"message_start","message":{"content":[],"usage":{"input_tokens":12,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":4}}
+
+`)
+	cl := newTestClient(s)
+	funcs, err := infer.Functions(new(customerService))
+	req.Nil(err)
+	tool, err := infer.Tool(funcs)
+	req.Nil(err)
+
+	var text strings.Builder
+	for chunk, err := range cl.GenerateTextStream(context.Background(), ai.NewText("Say hello to jane."), []*ai.Tool{tool}) {
+		req.Nil(err)
+		text.WriteString(chunk.Text)
+	}
+	req.Equal("Checking. Hello.", text.String())
+	req.Len(s.Requests(), 2)
+	// The thinking block must precede the text and the tool use in the stored turn.
+	msg := s.Requests()[1]["messages"].([]any)[1].(map[string]any)
+	req.Equal("assistant", msg["role"])
+	var types []string
+	for _, bl := range msg["content"].([]any) {
+		types = append(types, bl.(map[string]any)["type"].(string))
+	}
+	req.Equal([]string{"thinking", "text", "tool_use"}, types)
+	req.Equal("sig", msg["content"].([]any)[0].(map[string]any)["signature"])
+}
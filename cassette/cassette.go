@@ -0,0 +1,233 @@
+// Package cassette implements an HTTP transport that records request/response pairs to a file
+// and replays them offline, which makes tests of LLM clients deterministic.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode is the mode of a recorder.
+type Mode int
+
+const (
+	// Replay serves recorded responses and fails on unmatched requests.
+	Replay Mode = iota
+	// Record forwards requests and records the responses.
+	Record
+)
+
+// Interaction is a recorded request/response pair.
+type Interaction struct {
+	Request  *Request  `json:"request"`
+	Response *Response `json:"response"`
+}
+
+// Request is a recorded request.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// redactedParams are the query parameters that are never recorded.
+var redactedParams = []string{"key", "api_key", "access_token"}
+
+// keptHeaders are the response headers that are recorded.
+var keptHeaders = []string{"Content-Type", "Retry-After"}
+
+// Recorder is an HTTP transport that records or replays interactions.
+// Request headers aren't recorded, so API keys never end up in a cassette.
+type Recorder struct {
+	// Transport is the transport used in record mode. If nil, [http.DefaultTransport] is used.
+	Transport    http.RoundTripper
+	path         string
+	mode         Mode
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+var _ http.RoundTripper = (*Recorder)(nil)
+
+// New creates a new recorder for the cassette file. In replay mode, the file is loaded.
+func New(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode}
+	if mode == Replay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &r.interactions); err != nil {
+			return nil, fmt.Errorf("cassette '%s': %w", path, err)
+		}
+		r.used = make([]bool, len(r.interactions))
+	}
+	return r, nil
+}
+
+// NewFromEnv creates a new recorder in record mode if the environment variable is set
+// and in replay mode otherwise.
+func NewFromEnv(path, env string) (*Recorder, error) {
+	if os.Getenv(env) != "" {
+		return New(path, Record)
+	}
+	return New(path, Replay)
+}
+
+// Client returns an HTTP client that uses the recorder.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Interactions returns the recorded interactions.
+func (r *Recorder) Interactions() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Interaction(nil), r.interactions...)
+}
+
+// RoundTrip executes a single HTTP transaction.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	out, body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recReq := &Request{Method: req.Method, URL: redactURL(req.URL), Body: string(body)}
+	if r.mode == Replay {
+		return r.replay(req, recReq)
+	}
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	recResp := &Response{StatusCode: resp.StatusCode, Header: make(http.Header), Body: string(respBody)}
+	for _, h := range keptHeaders {
+		if v := resp.Header.Values(h); len(v) > 0 {
+			recResp.Header[h] = v
+		}
+	}
+	r.mu.Lock()
+	r.interactions = append(r.interactions, &Interaction{Request: recReq, Response: recResp})
+	r.mu.Unlock()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+// replay returns the first unused recorded response that matches the request.
+func (r *Recorder) replay(req *http.Request, recReq *Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.interactions {
+		if r.used[i] || !matches(in.Request, recReq) {
+			continue
+		}
+		r.used[i] = true
+		header := in.Response.Header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette '%s': no recorded interaction for %s %s", r.path, recReq.Method, recReq.URL)
+}
+
+// Save writes the recorded interactions to the cassette file. It does nothing in replay mode.
+func (r *Recorder) Save() error {
+	if r.mode == Replay {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+// readBody reads and closes the request's body. It returns a clone of the request with the body
+// to be sent instead, since a round tripper mustn't modify the request.
+func readBody(req *http.Request) (*http.Request, []byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	out := req.Clone(req.Context())
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	out.Body, _ = out.GetBody()
+	return out, body, nil
+}
+
+func redactURL(u *url.URL) string {
+	u2 := *u
+	q := u2.Query()
+	for _, p := range redactedParams {
+		if q.Has(p) {
+			q.Set(p, "REDACTED")
+		}
+	}
+	u2.RawQuery = q.Encode()
+	return u2.String()
+}
+
+func matches(a, b *Request) bool {
+	return a.Method == b.Method && a.URL == b.URL && canonical(a.Body) == canonical(b.Body)
+}
+
+// canonical returns a canonical form of a JSON body so that the key order doesn't matter.
+func canonical(body string) string {
+	var v any
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return body
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return string(data)
+}
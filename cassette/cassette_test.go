@@ -0,0 +1,109 @@
+package cassette
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phomola/ai-go/gemini/ai"
+	"github.com/phomola/ai-go/internal/stub"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+func newClient(t *testing.T, baseURL string, rec *Recorder) *ai.Client {
+	cl, err := ai.NewClientWithConfig(context.Background(), &genai.ClientConfig{
+		APIKey:      "secret-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPClient:  rec.Client(),
+		HTTPOptions: genai.HTTPOptions{BaseURL: baseURL},
+	}, ai.Gemini3FlashPreview)
+	require.Nil(t, err)
+	return cl
+}
+
+func TestRecordReplay(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lookup.json")
+
+	responses := []string{
+		`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"lookup","args":{"id":"a"}}}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"It is A."}]}}]}`,
+	}
+	s := stub.NewServer(t, "/v1beta/models/"+string(ai.Gemini3FlashPreview)+":generateContent", http.Header{"x-goog-api-key": {"secret-key"}}, responses...)
+
+	tools := []*ai.Tool{stub.LookupTool(t)}
+
+	rec, err := New(path, Record)
+	req.Nil(err)
+	resp, err := newClient(t, s.URL, rec).GenerateText(ctx, ai.NewText("What is a?"), tools)
+	req.Nil(err)
+	req.Equal("It is A.", resp.String())
+	req.Equal(2, len(rec.Interactions()))
+	req.Nil(rec.Save())
+	s.Close()
+
+	data, err := os.ReadFile(path)
+	req.Nil(err)
+	req.NotContains(string(data), "secret-key")
+
+	rec, err = New(path, Replay)
+	req.Nil(err)
+	cl := newClient(t, s.URL, rec)
+	resp, err = cl.GenerateText(ctx, ai.NewText("What is a?"), tools)
+	req.Nil(err)
+	req.Equal("It is A.", resp.String())
+	req.Equal(2, resp.Steps())
+
+	// All interactions have been used.
+	_, err = cl.GenerateText(ctx, ai.NewText("What is a?"), tools)
+	req.ErrorContains(err, "no recorded interaction")
+
+	_, err = cl.GenerateText(ctx, ai.NewText("What is b?"), nil)
+	req.ErrorContains(err, "no recorded interaction")
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestRoundTripKeepsRequest(t *testing.T) {
+	req := require.New(t)
+
+	s := stub.NewServer(t, "/echo", nil, `{"ok":true}`)
+	rec, err := New(filepath.Join(t.TempDir(), "echo.json"), Record)
+	req.Nil(err)
+	body := &closeRecorder{Reader: strings.NewReader(`{"q":1}`)}
+	r, err := http.NewRequest(http.MethodPost, s.URL+"/echo", body)
+	req.Nil(err)
+
+	resp, err := rec.RoundTrip(r)
+	req.Nil(err)
+	req.Nil(resp.Body.Close())
+	req.Equal(http.StatusOK, resp.StatusCode)
+	req.Same(r, resp.Request)
+	// The caller's request isn't modified and its body is closed.
+	req.Same(body, r.Body.(*closeRecorder))
+	req.True(body.closed)
+	req.Equal([]map[string]any{{"q": float64(1)}}, s.Requests())
+	req.Equal(`{"q":1}`, rec.Interactions()[0].Request.Body)
+}
+
+func TestRedactURL(t *testing.T) {
+	req := require.New(t)
+
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/v1/models?key=secret&alt=sse", nil)
+	req.Equal("https://example.com/v1/models?alt=sse&key=REDACTED", redactURL(r.URL))
+}
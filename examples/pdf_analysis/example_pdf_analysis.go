@@ -4,12 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"mime"
-	"net/http"
-	"os"
-	"path/filepath"
 
 	"github.com/phomola/ai-go/gemini/ai"
 )
@@ -32,37 +27,30 @@ func main() {
 	}
 	fileName := flag.Arg(0)
 
-	ext := filepath.Ext(fileName)
-	if ext == "" {
-		log.Fatal("no file extension")
-	}
-	mimeType := mime.TypeByExtension(ext)
-	if mimeType == "" {
-		log.Fatal("can't infer mime type")
-	}
+	ctx := context.Background()
 
-	f, err := os.Open(fileName)
+	cl, err := ai.NewClient(ctx, ai.Gemini3ProPreview)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer f.Close()
-	b, err := io.ReadAll(f)
+
+	// The file is uploaded to the Files API, so it can be larger than the inline limit.
+	files := cl.NewFileRegistry()
+	defer files.DeleteAll(ctx)
+	f, err := files.UploadPath(ctx, fileName)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	mimeType2 := http.DetectContentType(b)
-	fmt.Println(mimeType, mimeType2)
-
-	ctx := context.Background()
-
-	cl, err := ai.NewClient(ctx, ai.Gemini3ProPreview)
+	in, err := ai.NewContent().
+		UploadedFile(f).
+		Text("Extract relevant information for the CV file.").
+		Build()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	resp, err := cl.Generate[CV](ctx,
-		ai.NewTextWithBytes("Extract relevant information for the CV file.", b, mimeType), nil)
+	resp, err := cl.Generate[CV](ctx, in, nil)
 	if err != nil {
 		log.Fatal(err)
 	}
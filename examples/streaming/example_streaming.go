@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/phomola/ai-go/gemini/ai"
+)
+
+func main() {
+	ctx := context.Background()
+
+	cl, err := ai.NewClient(ctx, ai.Gemini3FlashPreview)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for chunk, err := range cl.GenerateTextStream(ctx, ai.NewText("Write a short poem about Go."), nil) {
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(chunk.Text)
+		if chunk.Usage != nil {
+			fmt.Printf("\n\ntokens: %d\n", chunk.Usage.TotalTokenCount)
+		}
+	}
+}
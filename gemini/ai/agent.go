@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+type agent struct {
+	cl            *Client
+	functions     map[string]func(context.Context, map[string]any) (map[string]any, error)
+	maxSteps      int
+	maxToolErrors int
+	toolErrors    int
+	step          int
+	toolCalls     []*ToolCall
+}
+
+func (cl *Client) newAgent(tools []*Tool) *agent {
+	a := &agent{
+		cl:            cl,
+		functions:     make(map[string]func(context.Context, map[string]any) (map[string]any, error)),
+		maxSteps:      cl.MaxSteps,
+		maxToolErrors: cl.MaxToolErrors,
+	}
+	for _, t := range tools {
+		maps.Copy(a.functions, t.Functions)
+	}
+	if a.maxSteps <= 0 {
+		a.maxSteps = DefaultMaxSteps
+	}
+	if a.maxToolErrors <= 0 {
+		a.maxToolErrors = DefaultMaxToolErrors
+	}
+	return a
+}
+
+// runTools executes the function calls and returns a content with their responses in the original order.
+func (a *agent) runTools(ctx context.Context, calls []*genai.FunctionCall) (*genai.Content, error) {
+	a.step++
+	results := a.callFunctions(ctx, calls)
+	parts := make([]*genai.Part, 0, len(calls))
+	for i, call := range calls {
+		a.toolCalls = append(a.toolCalls, &ToolCall{
+			Step:     a.step,
+			Name:     call.Name,
+			Args:     call.Args,
+			Output:   results[i].out,
+			Err:      results[i].err,
+			Duration: results[i].duration,
+		})
+		var response map[string]any
+		if err := results[i].err; err != nil {
+			a.toolErrors++
+			if a.toolErrors > a.maxToolErrors {
+				return nil, fmt.Errorf("function '%s': %w", call.Name, err)
+			}
+			response = map[string]any{"error": err.Error()}
+		} else {
+			a.toolErrors = 0
+			response = map[string]any{"output": results[i].out}
+		}
+		part := genai.NewPartFromFunctionResponse(call.Name, response)
+		part.FunctionResponse.ID = call.ID
+		parts = append(parts, part)
+	}
+	return genai.NewContentFromParts(parts, genai.RoleUser), nil
+}
+
+type callResult struct {
+	out      map[string]any
+	err      error
+	duration time.Duration
+}
+
+func (a *agent) callFunctions(ctx context.Context, calls []*genai.FunctionCall) []callResult {
+	results := make([]callResult, len(calls))
+	limit := a.cl.MaxConcurrentCalls
+	if limit <= 0 {
+		limit = len(calls)
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			start := time.Now()
+			results[i].out, results[i].err = a.callFunction(ctx, call)
+			results[i].duration = time.Since(start)
+		})
+	}
+	wg.Wait()
+	return results
+}
+
+func (a *agent) callFunction(ctx context.Context, call *genai.FunctionCall) (map[string]any, error) {
+	f, ok := a.functions[call.Name]
+	if !ok {
+		return nil, fmt.Errorf("tool function '%s' unknown", call.Name)
+	}
+	if a.cl.ToolTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.cl.ToolTimeout)
+		defer cancel()
+	}
+	return f(ctx, call.Args)
+}
@@ -2,18 +2,55 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"maps"
+	"slices"
+	"time"
 
-	"github.com/fealsamh/go-utils/nocopy"
+	"github.com/google/jsonschema-go/jsonschema"
 	"google.golang.org/genai"
 )
 
+// DefaultMaxSteps is the default maximum number of model calls in a single generation.
+const DefaultMaxSteps = 10
+
+// DefaultMaxToolErrors is the default number of consecutive tool errors reported back to the model.
+const DefaultMaxToolErrors = 3
+
+// ErrMaxSteps is returned when the model keeps calling tools after the maximum number of steps.
+var ErrMaxSteps = errors.New("maximum number of steps reached")
+
 // Client is an LLM client.
 type Client struct {
-	cl    *genai.Client
-	model Model
+	backend Backend
+	genai   *genai.Client
+	model   Model
+	options []Option
+	// MaxSteps is the maximum number of model calls in a single generation.
+	// If zero, [DefaultMaxSteps] is used.
+	MaxSteps int
+	// MaxToolErrors is the number of consecutive failed tool calls that are reported
+	// back to the model. The generation is aborted on the next consecutive failure.
+	// If zero, [DefaultMaxToolErrors] is used.
+	MaxToolErrors int
+	// MaxConcurrentCalls limits the number of tool calls executed concurrently.
+	// If zero, all calls requested by the model in one step run concurrently.
+	MaxConcurrentCalls int
+	// ToolTimeout is the timeout for a single tool call. If zero, only the context deadline applies.
+	ToolTimeout time.Duration
+	// RetryPolicy specifies how failed model calls are retried. If nil, they aren't retried.
+	RetryPolicy *RetryPolicy
+	// Limiter limits the rate of model calls. If nil, the rate isn't limited.
+	Limiter *Limiter
+	// Cache caches the responses of non-streaming model calls. If nil, they aren't cached.
+	Cache Cache
+	// CacheTTL is the time after which cached responses expire. If zero, they don't expire.
+	CacheTTL time.Duration
+	// MaxInputTokens is the estimated size of the input above which it's truncated by Truncator.
+	// If zero, the input isn't truncated.
+	MaxInputTokens int
+	// Truncator truncates the input. If nil, [DropOldest] is used.
+	Truncator Truncator
 }
 
 // Model specifies an LLM model.
@@ -28,88 +65,153 @@ const (
 	Gemini31ProPreview Model = "gemini-3.1-pro-preview"
 )
 
-// NewClient creates a new client.
-func NewClient(ctx context.Context, model Model) (*Client, error) {
-	cl, err := genai.NewClient(ctx, nil)
+// NewClient creates a new Gemini client.
+func NewClient(ctx context.Context, model Model, opts ...Option) (*Client, error) {
+	return NewClientWithConfig(ctx, nil, model, opts...)
+}
+
+// NewClientWithConfig creates a new Gemini client with the given configuration,
+// e.g. with a custom HTTP client.
+func NewClientWithConfig(ctx context.Context, config *genai.ClientConfig, model Model, opts ...Option) (*Client, error) {
+	cl, err := genai.NewClient(ctx, config)
 	if err != nil {
 		return nil, err
 	}
-	return &Client{cl: cl, model: model}, nil
+	c := NewClientWithBackend(cl.Models, model, opts...)
+	c.genai = cl
+	return c, nil
+}
+
+// NewClientWithBackend creates a new client with the given backend.
+func NewClientWithBackend(backend Backend, model Model, opts ...Option) *Client {
+	return &Client{backend: backend, model: model, options: opts}
 }
 
 // GenerateText generates a text response.
-func (cl *Client) GenerateText(ctx context.Context, in []*genai.Content, tools []*Tool) (*Response, error) {
-	genaiTools := make([]*genai.Tool, 0, len(tools))
-	for _, t := range tools {
-		genaiTools = append(genaiTools, t.tool())
-	}
-	var config *genai.GenerateContentConfig
-	if len(genaiTools) > 0 {
-		config = &genai.GenerateContentConfig{Tools: genaiTools}
-	}
-	resp, err := cl.generate(ctx, in, config, tools)
-	if err != nil {
-		return nil, err
-	}
-	return &Response{resp: resp}, nil
+// If it fails after a model call, the response so far is returned with the error.
+func (cl *Client) GenerateText(ctx context.Context, in []*genai.Content, tools []*Tool, opts ...Option) (*Response, error) {
+	return cl.generate(ctx, in, cl.newOptions(tools, opts), tools)
+}
+
+// GenerateJSON generates a JSON response conforming to the schema.
+// If it fails after a model call, the response so far is returned with the error.
+func (cl *Client) GenerateJSON(ctx context.Context, in []*genai.Content, schema *jsonschema.Schema, tools []*Tool, opts ...Option) (*Response, error) {
+	o := cl.newOptions(tools, opts)
+	o.config.ResponseMIMEType = "application/json"
+	o.config.ResponseJsonSchema = schema
+	return cl.generate(ctx, in, o, tools)
 }
 
 // Generate generates a structured response.
-func (cl *Client) Generate[T any](ctx context.Context,  in []*genai.Content, tools []*Tool) (*T, error) {
+func (cl *Client) Generate[T any](ctx context.Context,  in []*genai.Content, tools []*Tool, opts ...Option) (*T, error) {
+	return Generate[T](ctx, cl, in, tools, opts...)
+}
+
+// Generate generates a structured response with an LLM.
+func Generate[T any](ctx context.Context, llm LLM, in []*genai.Content, tools []*Tool, opts ...Option) (*T, error) {
+	obj, _, err := generateStructured[T](ctx, llm, in, tools, opts)
+	return obj, err
+}
+
+// generateStructured generates a structured response, repairing invalid responses.
+// If it fails after a response was received, the last response is returned with the error,
+// so that the usage of all attempts is known.
+func generateStructured[T any](ctx context.Context, llm LLM, in []*genai.Content, tools []*Tool, opts []Option) (*T, *Response, error) {
 	schema, err := schemaFor[T]()
 	if err != nil {
-		return nil, err
-	}
-	genaiTools := make([]*genai.Tool, 0, len(tools))
-	for _, t := range tools {
-		genaiTools = append(genaiTools, t.tool())
-	}
-	config := &genai.GenerateContentConfig{
-		ResponseMIMEType:   "application/json",
-		ResponseJsonSchema: schema,
-	}
-	if len(genaiTools) > 0 {
-		config.Tools = genaiTools
+		return nil, nil, err
 	}
-	resp, err := cl.generate(ctx, in, config, tools)
+	rs, err := schema.Resolve(nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	var obj T
-	if err := json.Unmarshal(nocopy.Bytes(resp.Text()), &obj); err != nil {
-		return nil, err
+	repairs := maxRepairs(llm, opts)
+	usage := new(genai.GenerateContentResponseUsageMetadata)
+	var last *Response
+	for attempt := 0; ; attempt++ {
+		resp, err := llm.GenerateJSON(ctx, in, schema, tools, opts...)
+		if err != nil {
+			if resp != nil {
+				addUsage(usage, resp.usage)
+				resp.usage = usage
+				last = resp
+			}
+			return nil, last, err
+		}
+		// The usage of the response includes the failed attempts.
+		addUsage(usage, resp.usage)
+		resp.usage = usage
+		last = resp
+		obj, err := decodeStructured[T](resp.String(), rs)
+		if err == nil {
+			return obj, resp, nil
+		}
+		if attempt >= repairs {
+			return nil, resp, fmt.Errorf("invalid response: %w", err)
+		}
+		in = append(resp.Transcript(), repairPrompt(err))
 	}
-	return &obj, nil
 }
 
-func (cl *Client) generate(ctx context.Context, in []*genai.Content, config *genai.GenerateContentConfig, tools []*Tool) (*genai.GenerateContentResponse, error) {
-	resp, err := cl.cl.Models.GenerateContent(ctx, string(cl.model), in, config)
-	if err != nil {
-		return nil, err
+func (cl *Client) generate(ctx context.Context, in []*genai.Content, o *options, tools []*Tool) (*Response, error) {
+	a := cl.newAgent(tools)
+	transcript := slices.Clone(in)
+	usage := new(genai.GenerateContentResponseUsageMetadata)
+	var last *genai.GenerateContentResponse
+	// fail returns the error with the response so far, so that the usage of the steps is known.
+	fail := func(steps int, err error) (*Response, error) {
+		if last == nil {
+			return nil, err
+		}
+		return &Response{resp: last, steps: steps, transcript: transcript, usage: usage, toolCalls: a.toolCalls}, err
 	}
-	if len(resp.FunctionCalls()) > 0 {
-		functions := make(map[string]func(context.Context, map[string]any) (map[string]any, error))
-		for _, t := range tools {
-			maps.Copy(functions, t.Functions)
+	for step := 1; ; step++ {
+		var err error
+		if transcript, err = cl.truncate(ctx, transcript); err != nil {
+			return fail(step-1, err)
 		}
-		in = append(in, resp.Candidates[0].Content)
-		for _, call := range resp.FunctionCalls() {
-			f, ok := functions[call.Name]
-			if !ok {
-				return nil, fmt.Errorf("tool function '%s' unknown", call.Name)
-			}
-			out, err := f(ctx, call.Args)
-			if err != nil {
-				return nil, err
-			}
-			in = append(in, genai.NewContentFromFunctionResponse(call.Name, map[string]any{"output": out}, ""))
+		resp, err := cl.cachedGenerateContent(ctx, transcript, o, func() (resp *genai.GenerateContentResponse, err error) {
+			err = cl.RetryPolicy.retry(ctx, func() error {
+				ev, err := cl.Limiter.reserve(ctx, transcript)
+				if err != nil {
+					return err
+				}
+				resp, err = cl.backend.GenerateContent(ctx, string(cl.model), transcript, &o.config)
+				if err != nil {
+					return err
+				}
+				cl.Limiter.adjust(ev, resp.UsageMetadata)
+				return nil
+			})
+			return resp, err
+		})
+		if err != nil {
+			return fail(step-1, err)
+		}
+		last = resp
+		addUsage(usage, resp.UsageMetadata)
+		if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
+			transcript = append(transcript, resp.Candidates[0].Content)
+		}
+		calls := resp.FunctionCalls()
+		if len(calls) == 0 {
+			return &Response{
+				resp:       resp,
+				steps:      step,
+				transcript: transcript,
+				usage:      usage,
+				toolCalls:  a.toolCalls,
+			}, nil
+		}
+		if step >= a.maxSteps {
+			return fail(step, fmt.Errorf("%w (%d)", ErrMaxSteps, a.maxSteps))
 		}
-		resp, err = cl.cl.Models.GenerateContent(ctx, string(cl.model), in, config)
+		content, err := a.runTools(ctx, calls)
 		if err != nil {
-			return nil, err
+			return fail(step, err)
 		}
+		transcript = append(transcript, content)
 	}
-	return resp, err
 }
 
 // NewText creates a new text content.
@@ -139,9 +241,23 @@ func NewTextWithBytes(text string, data []byte, mimeType string) []*genai.Conten
 
 // Response is an LLM response.
 type Response struct {
-	resp *genai.GenerateContentResponse
+	resp       *genai.GenerateContentResponse
+	steps      int
+	transcript []*genai.Content
+	usage      *genai.GenerateContentResponseUsageMetadata
+	toolCalls  []*ToolCall
 }
 
 func (resp *Response) String() string {
 	return resp.resp.Text()
 }
+
+// Steps returns the number of model calls made to produce the response.
+func (resp *Response) Steps() int {
+	return resp.steps
+}
+
+// Transcript returns the input contents followed by all model and tool turns.
+func (resp *Response) Transcript() []*genai.Content {
+	return resp.transcript
+}
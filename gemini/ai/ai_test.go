@@ -0,0 +1,282 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+type fakeServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses []string
+	requests  []map[string]any
+}
+
+func newFakeServer(t *testing.T, responses ...string) *fakeServer {
+	s := &fakeServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, req)
+		if len(s.responses) == 0 {
+			http.Error(w, "no more responses", http.StatusInternalServerError)
+			return
+		}
+		if rest, ok := strings.CutPrefix(s.responses[0], "status:"); ok {
+			s.responses = s.responses[1:]
+			code, body, _ := strings.Cut(rest, "\n")
+			status, _ := strconv.Atoi(code)
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+			return
+		}
+		if strings.Contains(r.URL.Path, "streamGenerateContent") {
+			w.Header().Set("Content-Type", "text/event-stream")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.Write([]byte(s.responses[0]))
+		s.responses = s.responses[1:]
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestClient(t *testing.T, s *fakeServer) *Client {
+	cl, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: s.URL},
+	})
+	require.Nil(t, err)
+	return NewClientWithBackend(cl.Models, Gemini3FlashPreview)
+}
+
+func functionCall(name, args string) string {
+	return `{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"` + name + `","args":` + args + `}}]}}]}`
+}
+
+func errorResponse(code int, body string) string {
+	return "status:" + strconv.Itoa(code) + "\n" + body
+}
+
+func functionCalls(calls ...string) string {
+	return `{"candidates":[{"content":{"role":"model","parts":[` + strings.Join(calls, ",") + `]}}]}`
+}
+
+func sse(chunks ...string) string {
+	var sb strings.Builder
+	for _, c := range chunks {
+		sb.WriteString("data: " + c + "\n\n")
+	}
+	return sb.String()
+}
+
+func textResponse(text string) string {
+	return `{"candidates":[{"content":{"role":"model","parts":[{"text":"` + text + `"}]}}]}`
+}
+
+type lookupInput struct {
+	ID string `json:"id"`
+}
+
+type lookupOutput struct {
+	Value string `json:"value"`
+}
+
+func lookupTool(t *testing.T, values map[string]string) *Tool {
+	var tool Tool
+	require.Nil(t, AddFunction(&tool, "lookup", "Looks up a value.", func(_ context.Context, in *lookupInput) (*lookupOutput, error) {
+		return &lookupOutput{Value: values[in.ID]}, nil
+	}))
+	return &tool
+}
+
+func TestAgentLoop(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t,
+		functionCall("lookup", `{"id":"customer"}`),
+		functionCall("lookup", `{"id":"order"}`),
+		textResponse("shipped"),
+	)
+	cl := newTestClient(t, s)
+	tool := lookupTool(t, map[string]string{"customer": "order", "order": "shipped"})
+
+	resp, err := cl.GenerateText(context.Background(), NewText("Where is my order?"), []*Tool{tool})
+	req.Nil(err)
+	req.Equal("shipped", resp.String())
+	req.Equal(3, resp.Steps())
+	req.Equal(6, len(resp.Transcript()))
+	req.Equal(3, len(s.requests))
+	req.Equal(5, len(s.requests[2]["contents"].([]any)))
+	last := resp.Transcript()[4]
+	req.Equal(map[string]any{"output": map[string]any{"value": "shipped"}}, last.Parts[0].FunctionResponse.Response)
+}
+
+func TestAgentLoopMaxSteps(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t,
+		functionCall("lookup", `{"id":"a"}`),
+		functionCall("lookup", `{"id":"a"}`),
+	)
+	cl := newTestClient(t, s)
+	cl.MaxSteps = 2
+
+	_, err := cl.GenerateText(context.Background(), NewText("Loop."), []*Tool{lookupTool(t, nil)})
+	req.True(errors.Is(err, ErrMaxSteps))
+	req.Equal(2, len(s.requests))
+}
+
+func TestToolErrorsFedBack(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t,
+		functionCall("lookup", `{"id":1}`),
+		functionCall("lookup", `{"id":"a"}`),
+		textResponse("done"),
+	)
+	cl := newTestClient(t, s)
+
+	resp, err := cl.GenerateText(context.Background(), NewText("Look up a."), []*Tool{lookupTool(t, map[string]string{"a": "b"})})
+	req.Nil(err)
+	req.Equal("done", resp.String())
+	fr := resp.Transcript()[2].Parts[0].FunctionResponse
+	req.Contains(fr.Response["error"], "invalid arguments")
+}
+
+func TestToolErrorsAbort(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t,
+		functionCall("missing", `{}`),
+		functionCall("missing", `{}`),
+		functionCall("missing", `{}`),
+		textResponse("unreachable"),
+	)
+	cl := newTestClient(t, s)
+	cl.MaxToolErrors = 2
+
+	_, err := cl.GenerateText(context.Background(), NewText("Call it."), []*Tool{lookupTool(t, nil)})
+	req.NotNil(err)
+	req.Equal("function 'missing': tool function 'missing' unknown", err.Error())
+	// Both errors were reported back to the model.
+	req.Equal(3, len(s.requests))
+	contents := s.requests[2]["contents"].([]any)
+	req.Equal(5, len(contents))
+}
+
+type sleepInput struct {
+	Millis int `json:"millis"`
+}
+
+type sleepOutput struct {
+	Millis int `json:"millis"`
+}
+
+func TestConcurrentToolCalls(t *testing.T) {
+	req := require.New(t)
+
+	call := func(ms string) string { return `{"functionCall":{"name":"sleep","args":{"millis":` + ms + `}}}` }
+	s := newFakeServer(t,
+		functionCalls(call("60"), call("20"), call("40"), call("10")),
+		textResponse("done"),
+	)
+	cl := newTestClient(t, s)
+	cl.MaxConcurrentCalls = 2
+
+	var running, maxRunning atomic.Int32
+	var tool Tool
+	req.Nil(AddFunction(&tool, "sleep", "Sleeps.", func(ctx context.Context, in *sleepInput) (*sleepOutput, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(in.Millis) * time.Millisecond)
+		return &sleepOutput{Millis: in.Millis}, nil
+	}))
+
+	resp, err := cl.GenerateText(context.Background(), NewText("Sleep."), []*Tool{&tool})
+	req.Nil(err)
+	req.Equal(int32(2), maxRunning.Load())
+	parts := resp.Transcript()[2].Parts
+	req.Equal(4, len(parts))
+	for i, ms := range []int{60, 20, 40, 10} {
+		req.Equal(map[string]any{"millis": ms}, parts[i].FunctionResponse.Response["output"])
+	}
+}
+
+func TestToolTimeout(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t,
+		functionCall("wait", `{}`),
+		textResponse("done"),
+	)
+	cl := newTestClient(t, s)
+	cl.ToolTimeout = 10 * time.Millisecond
+
+	var tool Tool
+	req.Nil(AddFunction(&tool, "wait", "Waits.", func(ctx context.Context, _ *struct{}) (*struct{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+
+	resp, err := cl.GenerateText(context.Background(), NewText("Wait."), []*Tool{&tool})
+	req.Nil(err)
+	req.Equal(context.DeadlineExceeded.Error(), resp.Transcript()[2].Parts[0].FunctionResponse.Response["error"])
+}
+
+func TestResponseMetadata(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Checking.","thought":true},{"functionCall":{"name":"lookup","args":{"id":"a"}}}]}}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"thoughtsTokenCount":3,"totalTokenCount":18}}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"It is b."}]},"finishReason":"STOP","safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"NEGLIGIBLE"}],"citationMetadata":{"citationSources":[{"uri":"https://example.com"}]}}],"usageMetadata":{"promptTokenCount":20,"candidatesTokenCount":4,"cachedContentTokenCount":8,"totalTokenCount":24}}`,
+	)
+	cl := newTestClient(t, s)
+
+	resp, err := cl.GenerateText(context.Background(), NewText("What is a?"), []*Tool{lookupTool(t, map[string]string{"a": "b"})}, WithThoughts())
+	req.Nil(err)
+	req.Equal(30, resp.PromptTokens())
+	req.Equal(9, resp.OutputTokens())
+	req.Equal(3, resp.ThinkingTokens())
+	req.Equal(8, resp.CachedTokens())
+	req.Equal(42, resp.TotalTokens())
+	req.Equal(genai.FinishReasonStop, resp.FinishReason())
+	req.Equal(1, len(resp.SafetyRatings()))
+	req.Equal("https://example.com", resp.Citations()[0].URI)
+	req.Nil(resp.GroundingMetadata())
+	req.Equal("Checking.", resp.Thoughts())
+	req.Equal(1, len(resp.Candidates()))
+	req.Equal(1, len(resp.ToolCalls()))
+	call := resp.ToolCalls()[0]
+	req.Equal(1, call.Step)
+	req.Equal("lookup", call.Name)
+	req.Equal(map[string]any{"id": "a"}, call.Args)
+	req.Equal(map[string]any{"value": "b"}, call.Output)
+	req.Nil(call.Err)
+	req.Equal(true, s.requests[0]["generationConfig"].(map[string]any)["thinkingConfig"].(map[string]any)["includeThoughts"])
+}
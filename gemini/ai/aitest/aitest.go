@@ -0,0 +1,236 @@
+// Package aitest provides a scripted fake model for testing code that uses tools.
+// The fake model is a backend, so the tool calls go through the same dispatch
+// as with a real model, including argument validation.
+package aitest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/phomola/ai-go/gemini/ai"
+	"google.golang.org/genai"
+)
+
+// Model is the model name used by clients created by [Script.Client].
+const Model ai.Model = "aitest"
+
+var _ ai.Backend = (*Script)(nil)
+
+// ErrExhausted is returned when the model is called after the end of the script.
+var ErrExhausted = errors.New("script exhausted")
+
+// Script is a fake model that plays a script of turns.
+type Script struct {
+	t        testing.TB
+	mu       sync.Mutex
+	steps    []*step
+	requests []*Request
+}
+
+// Request is a request received by the fake model.
+type Request struct {
+	Model    string
+	Contents []*genai.Content
+	Config   *genai.GenerateContentConfig
+}
+
+type step struct {
+	resp   *genai.GenerateContentResponse
+	err    error
+	expect func(contents []*genai.Content)
+}
+
+// New creates a new script. The test fails if the script isn't played to the end.
+func New(t testing.TB) *Script {
+	s := &Script{t: t}
+	t.Cleanup(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if len(s.steps) > 0 {
+			t.Errorf("aitest: %d script steps not reached", len(s.steps))
+		}
+	})
+	return s
+}
+
+// Client creates a new client with the script as its backend.
+func (s *Script) Client(opts ...ai.Option) *ai.Client {
+	return ai.NewClientWithBackend(s, Model, opts...)
+}
+
+// Call adds a model turn with a function call. The arguments are a map or a struct.
+func (s *Script) Call(name string, args any) *Script {
+	return s.Calls(s.FunctionCall(name, args))
+}
+
+// Calls adds a model turn with several function calls.
+func (s *Script) Calls(calls ...*genai.FunctionCall) *Script {
+	parts := make([]*genai.Part, len(calls))
+	for i, call := range calls {
+		parts[i] = &genai.Part{FunctionCall: call}
+	}
+	return s.turn(genai.NewContentFromParts(parts, genai.RoleModel))
+}
+
+// FunctionCall creates a function call for [Script.Calls].
+func (s *Script) FunctionCall(name string, args any) *genai.FunctionCall {
+	var m map[string]any
+	if err := convert(args, &m); err != nil {
+		s.t.Fatalf("aitest: arguments of '%s': %v", name, err)
+	}
+	return &genai.FunctionCall{Name: name, Args: m}
+}
+
+// Text adds a model turn with a text.
+func (s *Script) Text(text string) *Script {
+	return s.turn(genai.NewContentFromText(text, genai.RoleModel))
+}
+
+// JSON adds a model turn with a value encoded as JSON.
+func (s *Script) JSON(v any) *Script {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.t.Fatalf("aitest: %v", err)
+	}
+	return s.Text(string(data))
+}
+
+// Fail adds a model turn that fails with the error.
+func (s *Script) Fail(err error) *Script {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, &step{err: err})
+	return s
+}
+
+// ExpectOutput expects that the next request contains the output of the function.
+// The output is compared with want as JSON.
+func (s *Script) ExpectOutput(name string, want any) *Script {
+	return s.expect(func(contents []*genai.Content) {
+		resp := functionResponse(contents, name)
+		if resp == nil {
+			s.t.Errorf("aitest: no response of '%s'", name)
+			return
+		}
+		if errMsg, ok := resp["error"]; ok {
+			s.t.Errorf("aitest: '%s' failed: %v", name, errMsg)
+			return
+		}
+		var got, exp any
+		if err := convert(resp["output"], &got); err != nil {
+			s.t.Errorf("aitest: output of '%s': %v", name, err)
+			return
+		}
+		if err := convert(want, &exp); err != nil {
+			s.t.Errorf("aitest: expected output of '%s': %v", name, err)
+			return
+		}
+		if !reflect.DeepEqual(got, exp) {
+			gotJSON, _ := json.Marshal(got)
+			expJSON, _ := json.Marshal(exp)
+			s.t.Errorf("aitest: output of '%s' is %s, expected %s", name, gotJSON, expJSON)
+		}
+	})
+}
+
+// ExpectError expects that the next request contains an error of the function
+// that contains the substring.
+func (s *Script) ExpectError(name, substr string) *Script {
+	return s.expect(func(contents []*genai.Content) {
+		resp := functionResponse(contents, name)
+		if resp == nil {
+			s.t.Errorf("aitest: no response of '%s'", name)
+			return
+		}
+		errMsg, ok := resp["error"].(string)
+		if !ok {
+			s.t.Errorf("aitest: '%s' didn't fail", name)
+			return
+		}
+		if !strings.Contains(errMsg, substr) {
+			s.t.Errorf("aitest: error of '%s' is '%s', expected '%s'", name, errMsg, substr)
+		}
+	})
+}
+
+// Expect expects that the next request satisfies the check.
+func (s *Script) Expect(check func(t testing.TB, contents []*genai.Content)) *Script {
+	return s.expect(func(contents []*genai.Content) {
+		check(s.t, contents)
+	})
+}
+
+// Requests returns the requests received so far.
+func (s *Script) Requests() []*Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Request(nil), s.requests...)
+}
+
+// GenerateContent plays the next turn of the script.
+func (s *Script) GenerateContent(_ context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, &Request{Model: model, Contents: contents, Config: config})
+	for len(s.steps) > 0 && s.steps[0].expect != nil {
+		s.steps[0].expect(contents)
+		s.steps = s.steps[1:]
+	}
+	if len(s.steps) == 0 {
+		s.t.Errorf("aitest: model called after the end of the script")
+		return nil, ErrExhausted
+	}
+	st := s.steps[0]
+	s.steps = s.steps[1:]
+	return st.resp, st.err
+}
+
+// GenerateContentStream plays the next turn of the script as a single chunk.
+func (s *Script) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		yield(s.GenerateContent(ctx, model, contents, config))
+	}
+}
+
+func (s *Script) turn(content *genai.Content) *Script {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, &step{resp: &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{Content: content, FinishReason: genai.FinishReasonStop}},
+	}})
+	return s
+}
+
+func (s *Script) expect(f func(contents []*genai.Content)) *Script {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, &step{expect: f})
+	return s
+}
+
+// functionResponse returns the response of the function in the last content.
+func functionResponse(contents []*genai.Content, name string) map[string]any {
+	if len(contents) == 0 {
+		return nil
+	}
+	for _, p := range contents[len(contents)-1].Parts {
+		if p.FunctionResponse != nil && p.FunctionResponse.Name == name {
+			return p.FunctionResponse.Response
+		}
+	}
+	return nil
+}
+
+func convert(in, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
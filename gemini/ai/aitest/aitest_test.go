@@ -0,0 +1,40 @@
+package aitest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/phomola/ai-go/gemini/ai"
+	"github.com/phomola/ai-go/internal/stub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScript(t *testing.T) {
+	req := require.New(t)
+
+	s := New(t)
+	s.Call("lookup", &stub.LookupInput{ID: "a"}).
+		ExpectOutput("lookup", &stub.LookupOutput{Value: "A"}).
+		Call("lookup", map[string]any{"id": 1}).
+		ExpectError("lookup", "invalid arguments").
+		Calls(s.FunctionCall("lookup", map[string]any{"id": "b"}), s.FunctionCall("missing", nil)).
+		ExpectOutput("lookup", map[string]any{"value": "B"}).
+		ExpectError("missing", "unknown").
+		Text("Done.")
+
+	resp, err := s.Client().GenerateText(context.Background(), ai.NewText("Look up a and b."), []*ai.Tool{stub.LookupTool(t)})
+	req.Nil(err)
+	req.Equal("Done.", resp.String())
+	req.Equal(4, resp.Steps())
+	req.Equal(4, len(s.Requests()))
+	req.Equal("lookup", s.Requests()[0].Config.Tools[0].FunctionDeclarations[0].Name)
+}
+
+func TestScriptJSON(t *testing.T) {
+	req := require.New(t)
+
+	s := New(t).JSON(&stub.LookupOutput{Value: "x"})
+	out, err := ai.Generate[stub.LookupOutput](context.Background(), s.Client(), ai.NewText("Describe."), nil)
+	req.Nil(err)
+	req.Equal("x", out.Value)
+}
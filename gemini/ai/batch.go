@@ -0,0 +1,287 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"iter"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// DefaultBatchConcurrency is the default number of concurrent generations of a batch.
+const DefaultBatchConcurrency = 8
+
+// BatchConfig configures a batch generation.
+// Model calls are retried and rate limited according to the client's retry policy and limiter.
+type BatchConfig struct {
+	// Concurrency is the number of concurrent generations. If zero, [DefaultBatchConcurrency] is used.
+	Concurrency int
+	// Checkpoint is the path of a file to which successful results are appended.
+	// If the file exists, its results are reused, so an interrupted batch can be resumed.
+	Checkpoint string
+	// Progress is called after each finished item.
+	Progress func(BatchStats)
+	// Tools are passed to each generation.
+	Tools []*Tool
+	// Options are passed to each generation.
+	Options []Option
+}
+
+// BatchResult is the result of a single item of a batch.
+type BatchResult[T any] struct {
+	// Value is the generated value, or nil if the generation failed.
+	Value *T
+	// Err is the error of the generation.
+	Err error
+	// Resumed reports whether the value was read from the checkpoint.
+	Resumed bool
+}
+
+// BatchStats contains the metrics of a batch generation.
+type BatchStats struct {
+	// Completed is the number of finished items including the failed and resumed ones.
+	Completed int
+	// Failed is the number of failed items.
+	Failed int
+	// Resumed is the number of items read from the checkpoint.
+	Resumed int
+	// PromptTokens, OutputTokens, ThinkingTokens, CachedTokens and TotalTokens are
+	// the numbers of tokens used by the generations including the failed ones.
+	PromptTokens, OutputTokens, ThinkingTokens, CachedTokens, TotalTokens int
+	// Elapsed is the time since the start of the batch.
+	Elapsed time.Duration
+}
+
+// Throughput returns the number of generated items per second. Resumed items aren't counted.
+func (s BatchStats) Throughput() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Completed-s.Resumed) / s.Elapsed.Seconds()
+}
+
+// Cost returns the cost of the used tokens given the prices per million input and output tokens.
+// Thinking tokens are priced as output tokens.
+func (s BatchStats) Cost(inputPrice, outputPrice float64) float64 {
+	return (float64(s.PromptTokens)*inputPrice + float64(s.OutputTokens+s.ThinkingTokens)*outputPrice) / 1e6
+}
+
+// GenerateBatch generates structured responses for a batch of inputs, e.g. [slices.Values] of a slice.
+func (cl *Client) GenerateBatch[T any](ctx context.Context, inputs iter.Seq[[]*genai.Content], config *BatchConfig) ([]BatchResult[T], BatchStats, error) {
+	return GenerateBatch[T](ctx, cl, inputs, config)
+}
+
+// GenerateBatch generates structured responses for a batch of inputs with an LLM, e.g. [slices.Values] of a slice.
+// The results are in the order of the inputs. Failed items don't stop the batch, their errors are reported
+// in the results. An error is returned only if the context is cancelled or the checkpoint can't be written,
+// in which case the results are incomplete.
+func GenerateBatch[T any](ctx context.Context, llm LLM, inputs iter.Seq[[]*genai.Content], config *BatchConfig) ([]BatchResult[T], BatchStats, error) {
+	if config == nil {
+		config = new(BatchConfig)
+	}
+	start := time.Now()
+	var stats BatchStats
+	cp, err := openCheckpoint(config.Checkpoint)
+	if err != nil {
+		return nil, stats, err
+	}
+	defer cp.close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan batchJob)
+	go func() {
+		defer close(jobs)
+		i := 0
+		for in := range inputs {
+			select {
+			case jobs <- batchJob{index: i, in: in}:
+			case <-ctx.Done():
+				return
+			}
+			i++
+		}
+	}()
+
+	done := make(chan batchItem[T])
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Go(func() {
+			for job := range jobs {
+				done <- generateBatchItem[T](ctx, llm, job, cp, config)
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var results []BatchResult[T]
+	var cpErr error
+	for item := range done {
+		if item.index >= len(results) {
+			results = append(results, make([]BatchResult[T], item.index+1-len(results))...)
+		}
+		results[item.index] = item.result
+		stats.Completed++
+		switch {
+		case item.result.Err != nil:
+			stats.Failed++
+		case item.result.Resumed:
+			stats.Resumed++
+		default:
+			if err := cp.write(item.index, item.key, item.result.Value); err != nil && cpErr == nil {
+				cpErr = err
+				cancel()
+			}
+		}
+		if u := item.usage; u != nil {
+			stats.PromptTokens += int(u.PromptTokenCount)
+			stats.OutputTokens += int(u.CandidatesTokenCount)
+			stats.ThinkingTokens += int(u.ThoughtsTokenCount)
+			stats.CachedTokens += int(u.CachedContentTokenCount)
+			stats.TotalTokens += int(u.TotalTokenCount)
+		}
+		stats.Elapsed = time.Since(start)
+		if config.Progress != nil {
+			config.Progress(stats)
+		}
+	}
+	stats.Elapsed = time.Since(start)
+	if cpErr != nil {
+		return results, stats, cpErr
+	}
+	return results, stats, context.Cause(ctx)
+}
+
+type batchJob struct {
+	index int
+	in    []*genai.Content
+}
+
+type batchItem[T any] struct {
+	index  int
+	key    string
+	result BatchResult[T]
+	usage  *genai.GenerateContentResponseUsageMetadata
+}
+
+func generateBatchItem[T any](ctx context.Context, llm LLM, job batchJob, cp *checkpoint, config *BatchConfig) batchItem[T] {
+	item := batchItem[T]{index: job.index}
+	if cp != nil {
+		item.key = inputKey(job.in)
+		if data, ok := cp.entries[job.index]; ok && data.Key == item.key {
+			var v T
+			if err := json.Unmarshal(data.Value, &v); err == nil {
+				item.result = BatchResult[T]{Value: &v, Resumed: true}
+				return item
+			}
+		}
+	}
+	if err := context.Cause(ctx); err != nil {
+		item.result.Err = err
+		return item
+	}
+	obj, resp, err := generateStructured[T](ctx, llm, job.in, config.Tools, config.Options)
+	item.result = BatchResult[T]{Value: obj, Err: err}
+	if resp != nil {
+		item.usage = resp.Usage()
+	}
+	return item
+}
+
+// inputKey identifies an input in the checkpoint, so that results of changed inputs aren't reused.
+func inputKey(in []*genai.Content) string {
+	data, _ := json.Marshal(in)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkpoint is a file with a JSON line for each successful result of a batch.
+type checkpoint struct {
+	f       *os.File
+	entries map[int]checkpointEntry
+}
+
+type checkpointEntry struct {
+	Index int             `json:"index"`
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// openCheckpoint reads the results of the previous runs. It returns nil if the path is empty.
+func openCheckpoint(path string) (*checkpoint, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	cp := &checkpoint{f: f, entries: make(map[int]checkpointEntry)}
+	dec := json.NewDecoder(f)
+	var offset int64
+	for {
+		var e checkpointEntry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// The last line was written only partially, e.g. because of a crash.
+			if err := f.Truncate(offset); err != nil {
+				f.Close()
+				return nil, err
+			}
+			break
+		}
+		cp.entries[e.Index] = e
+		offset = dec.InputOffset()
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if offset > 0 {
+		if _, err := f.Write([]byte("\n")); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return cp, nil
+}
+
+func (cp *checkpoint) write(index int, key string, value any) error {
+	if cp == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(checkpointEntry{Index: index, Key: key, Value: data}); err != nil {
+		return err
+	}
+	_, err = cp.f.Write(buf.Bytes())
+	return err
+}
+
+func (cp *checkpoint) close() error {
+	if cp == nil {
+		return nil
+	}
+	return cp.f.Close()
+}
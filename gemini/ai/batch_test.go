@@ -0,0 +1,199 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+// ageBackend responds with a person whose age is the number in the input.
+// If the input is "invalid", the response is always invalid.
+type ageBackend struct {
+	Backend
+	calls atomic.Int32
+}
+
+func (b *ageBackend) GenerateContent(_ context.Context, _ string, contents []*genai.Content, _ *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	b.calls.Add(1)
+	text := `{"name":"invalid"}`
+	if in := contents[0].Parts[0].Text; in != "invalid" {
+		age, err := strconv.Atoi(in)
+		if err != nil {
+			return nil, errors.New("not a number")
+		}
+		text = fmt.Sprintf(`{"name":"p%d","age":%d}`, age, age)
+	}
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{Content: genai.NewContentFromText(text, genai.RoleModel)}},
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     10,
+			CandidatesTokenCount: 5,
+			TotalTokenCount:      15,
+		},
+	}, nil
+}
+
+func ageInputs(texts ...string) [][]*genai.Content {
+	var inputs [][]*genai.Content
+	for _, text := range texts {
+		inputs = append(inputs, NewText(text))
+	}
+	return inputs
+}
+
+func TestGenerateBatch(t *testing.T) {
+	req := require.New(t)
+
+	var texts []string
+	for i := range 20 {
+		texts = append(texts, strconv.Itoa(i))
+	}
+	texts[7] = "x"
+	cl := NewClientWithBackend(new(ageBackend), Gemini3FlashPreview)
+	var progress []BatchStats
+	results, stats, err := GenerateBatch[person](context.Background(), cl, slices.Values(ageInputs(texts...)), &BatchConfig{
+		Concurrency: 4,
+		Progress:    func(s BatchStats) { progress = append(progress, s) },
+	})
+	req.Nil(err)
+	req.Len(results, 20)
+	for i, r := range results {
+		if i == 7 {
+			req.ErrorContains(r.Err, "not a number")
+			req.Nil(r.Value)
+			continue
+		}
+		req.Nil(r.Err)
+		req.Equal(&person{Name: fmt.Sprintf("p%d", i), Age: i}, r.Value)
+	}
+	req.Equal(20, stats.Completed)
+	req.Equal(1, stats.Failed)
+	req.Equal(190, stats.PromptTokens)
+	req.Equal(95, stats.OutputTokens)
+	req.Equal(285, stats.TotalTokens)
+	req.InDelta(190*1.0/1e6+95*4.0/1e6, stats.Cost(1, 4), 1e-12)
+	req.Positive(stats.Throughput())
+	req.Len(progress, 20)
+	req.Equal(20, progress[19].Completed)
+}
+
+func TestGenerateBatchFailedUsage(t *testing.T) {
+	req := require.New(t)
+
+	b := new(ageBackend)
+	cl := NewClientWithBackend(b, Gemini3FlashPreview)
+	results, stats, err := GenerateBatch[person](context.Background(), cl, slices.Values(ageInputs("1", "invalid")), nil)
+	req.Nil(err)
+	req.ErrorContains(results[1].Err, "invalid response")
+	req.Equal(int32(2+DefaultMaxRepairs), b.calls.Load())
+	req.Equal(1, stats.Failed)
+	// The failed item is charged for all its attempts.
+	req.Equal(15*(2+DefaultMaxRepairs), stats.TotalTokens)
+	req.Equal(10*(2+DefaultMaxRepairs), stats.PromptTokens)
+}
+
+// toolBackend calls the lookup function and fails on the next call.
+type toolBackend struct {
+	Backend
+}
+
+func (toolBackend) GenerateContent(_ context.Context, _ string, contents []*genai.Content, _ *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	if len(contents) > 1 {
+		return nil, errors.New("backend failed")
+	}
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{Content: genai.NewContentFromFunctionCall("lookup", map[string]any{"id": "a"}, genai.RoleModel)}},
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     10,
+			CandidatesTokenCount: 5,
+			TotalTokenCount:      15,
+		},
+	}, nil
+}
+
+func TestGenerateBatchFailedAfterToolCall(t *testing.T) {
+	req := require.New(t)
+
+	cl := NewClientWithBackend(toolBackend{}, Gemini3FlashPreview)
+	results, stats, err := GenerateBatch[person](context.Background(), cl, slices.Values(ageInputs("1", "2")), &BatchConfig{
+		Tools: []*Tool{lookupTool(t, map[string]string{"a": "A"})},
+	})
+	req.Nil(err)
+	for _, r := range results {
+		req.ErrorContains(r.Err, "backend failed")
+	}
+	req.Equal(2, stats.Failed)
+	// The failed items are charged for the steps before the failure.
+	req.Equal(30, stats.TotalTokens)
+	req.Equal(20, stats.PromptTokens)
+}
+
+func TestGenerateBatchResume(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "batch.jsonl")
+	b := new(ageBackend)
+	cl := NewClientWithBackend(b, Gemini3FlashPreview)
+	config := &BatchConfig{Checkpoint: path}
+	results, stats, err := cl.GenerateBatch[person](ctx, slices.Values(ageInputs("1", "x", "3", "4")), config)
+	req.Nil(err)
+	req.Len(results, 4)
+	req.Equal(1, stats.Failed)
+	req.Equal(int32(4), b.calls.Load())
+
+	// A partially written line is discarded.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	req.Nil(err)
+	_, err = f.WriteString(`{"index":1,"key":"`)
+	req.Nil(err)
+	req.Nil(f.Close())
+
+	b.calls.Store(0)
+	results, stats, err = cl.GenerateBatch[person](ctx, slices.Values(ageInputs("1", "2", "3", "5")), config)
+	req.Nil(err)
+	req.Equal(int32(2), b.calls.Load())
+	req.Equal(2, stats.Resumed)
+	req.Equal(0, stats.Failed)
+	req.Equal(30, stats.TotalTokens)
+	for i, age := range []int{1, 2, 3, 5} {
+		req.Equal(age, results[i].Value.Age)
+		req.Equal(i == 0 || i == 2, results[i].Resumed)
+	}
+
+	b.calls.Store(0)
+	_, stats, err = cl.GenerateBatch[person](ctx, slices.Values(ageInputs("1", "2", "3", "5")), config)
+	req.Nil(err)
+	req.Equal(int32(0), b.calls.Load())
+	req.Equal(4, stats.Resumed)
+}
+
+func TestGenerateBatchCancel(t *testing.T) {
+	req := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cl := NewClientWithBackend(new(ageBackend), Gemini3FlashPreview)
+	results, stats, err := GenerateBatch[person](ctx, cl, slices.Values(ageInputs("1", "2", "3", "4")), &BatchConfig{
+		Concurrency: 1,
+		Progress: func(s BatchStats) {
+			if s.Completed == 2 {
+				cancel()
+			}
+		},
+	})
+	req.ErrorIs(err, context.Canceled)
+	req.Equal(len(results), stats.Completed)
+	req.Equal(2, results[1].Value.Age)
+	if len(results) == 4 {
+		req.ErrorIs(results[3].Err, context.Canceled)
+	}
+}
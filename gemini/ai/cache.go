@@ -0,0 +1,216 @@
+package ai
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Cache stores model responses.
+type Cache interface {
+	// Get returns the value for the key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value for the key. A zero TTL means the value doesn't expire.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+var (
+	_ Cache = (*MemoryCache)(nil)
+	_ Cache = (*DirCache)(nil)
+)
+
+// MemoryCache is an in-memory LRU cache.
+type MemoryCache struct {
+	capacity int
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache creates a new in-memory cache holding at most capacity entries.
+// A zero capacity means no limit.
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{capacity: capacity, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// Get returns the value for the key and whether it was found.
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*memoryEntry)
+	if expired(e.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	c.lru.MoveToFront(el)
+	return e.value, true, nil
+}
+
+// Set stores the value for the key.
+func (c *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &memoryEntry{key: key, value: value, expires: expiry(ttl)}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return nil
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	if c.capacity > 0 && c.lru.Len() > c.capacity {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.entries, el.Value.(*memoryEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of entries in the cache.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// DirCache is a cache that stores entries as files in a directory.
+type DirCache struct {
+	dir string
+}
+
+type dirEntry struct {
+	Expires time.Time `json:"expires,omitzero"`
+	Value   []byte    `json:"value"`
+}
+
+// NewDirCache creates a new cache in the directory, which is created if it doesn't exist.
+func NewDirCache(dir string) (*DirCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirCache{dir: dir}, nil
+}
+
+// Get returns the value for the key and whether it was found.
+func (c *DirCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	var e dirEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, false, err
+	}
+	if expired(e.Expires) {
+		if err := os.Remove(c.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, false, err
+		}
+		return nil, false, nil
+	}
+	return e.Value, true, nil
+}
+
+// Set stores the value for the key.
+func (c *DirCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	data, err := json.Marshal(&dirEntry{Expires: expiry(ttl), Value: value})
+	if err != nil {
+		return err
+	}
+	// The entry is renamed into place so that concurrent readers never see a partial file.
+	f, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.path(key))
+}
+
+func (c *DirCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func expired(t time.Time) bool {
+	return !t.IsZero() && time.Now().After(t)
+}
+
+// cacheKey returns a canonical hash of a model call.
+func cacheKey(model Model, contents []*genai.Content, config *genai.GenerateContentConfig) (string, error) {
+	data, err := json.Marshal(struct {
+		Model    Model                        `json:"model"`
+		Contents []*genai.Content             `json:"contents"`
+		Config   *genai.GenerateContentConfig `json:"config"`
+	}{model, contents, config})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// cachedGenerateContent calls the backend unless the response is cached.
+// Cached responses have no usage, since they cost nothing. Cache errors are ignored,
+// so that a failing cache never discards a response.
+func (cl *Client) cachedGenerateContent(ctx context.Context, contents []*genai.Content, o *options, call func() (*genai.GenerateContentResponse, error)) (*genai.GenerateContentResponse, error) {
+	if cl.Cache == nil || o.noCache {
+		return call()
+	}
+	key, err := cacheKey(cl.model, contents, &o.config)
+	if err != nil {
+		return call()
+	}
+	if data, ok, err := cl.Cache.Get(ctx, key); err == nil && ok {
+		var resp genai.GenerateContentResponse
+		if err := json.Unmarshal(data, &resp); err == nil {
+			resp.UsageMetadata = nil
+			return &resp, nil
+		}
+	}
+	resp, err := call()
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return resp, nil
+	}
+	if data, err := json.Marshal(resp); err == nil {
+		cl.Cache.Set(ctx, key, data, cl.CacheTTL)
+	}
+	return resp, nil
+}
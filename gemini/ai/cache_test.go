@@ -0,0 +1,126 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	c := NewMemoryCache(2)
+	req.Nil(c.Set(ctx, "a", []byte("1"), 0))
+	req.Nil(c.Set(ctx, "b", []byte("2"), 0))
+	_, ok, _ := c.Get(ctx, "a")
+	req.True(ok)
+	req.Nil(c.Set(ctx, "c", []byte("3"), 0))
+	req.Equal(2, c.Len())
+	_, ok, _ = c.Get(ctx, "b")
+	req.False(ok)
+	v, ok, err := c.Get(ctx, "a")
+	req.Nil(err)
+	req.True(ok)
+	req.Equal([]byte("1"), v)
+
+	req.Nil(c.Set(ctx, "d", []byte("4"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	_, ok, _ = c.Get(ctx, "d")
+	req.False(ok)
+}
+
+func TestDirCache(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	c, err := NewDirCache(t.TempDir())
+	req.Nil(err)
+	_, ok, err := c.Get(ctx, "a")
+	req.Nil(err)
+	req.False(ok)
+
+	req.Nil(c.Set(ctx, "a", []byte("1"), time.Hour))
+	v, ok, err := c.Get(ctx, "a")
+	req.Nil(err)
+	req.True(ok)
+	req.Equal([]byte("1"), v)
+
+	req.Nil(c.Set(ctx, "b", []byte("2"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	_, ok, err = c.Get(ctx, "b")
+	req.Nil(err)
+	req.False(ok)
+}
+
+func TestClientCache(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	s := newFakeServer(t, textResponse(`{\"value\":\"x\"}`), textResponse(`{\"value\":\"y\"}`), textResponse(`{\"value\":\"z\"}`))
+	cl := newTestClient(t, s)
+	cl.Cache = NewMemoryCache(10)
+
+	for range 3 {
+		out, err := Generate[lookupOutput](ctx, cl, NewText("Describe."), nil)
+		req.Nil(err)
+		req.Equal("x", out.Value)
+	}
+	req.Equal(1, len(s.requests))
+
+	out, err := Generate[lookupOutput](ctx, cl, NewText("Describe."), nil, WithoutCache())
+	req.Nil(err)
+	req.Equal("y", out.Value)
+
+	// The tool declarations are part of the key.
+	out, err = Generate[lookupOutput](ctx, cl, NewText("Describe."), []*Tool{lookupTool(t, nil)})
+	req.Nil(err)
+	req.Equal("z", out.Value)
+	req.Equal(3, len(s.requests))
+}
+
+func TestClientCacheUsage(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	s := newFakeServer(t, `{"candidates":[{"content":{"role":"model","parts":[{"text":"Hi."}]}}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":2,"totalTokenCount":12}}`)
+	cl := newTestClient(t, s)
+	cl.Cache = NewMemoryCache(10)
+
+	resp, err := cl.GenerateText(ctx, NewText("Hello."), nil)
+	req.Nil(err)
+	req.Equal(12, resp.TotalTokens())
+	resp, err = cl.GenerateText(ctx, NewText("Hello."), nil)
+	req.Nil(err)
+	req.Equal("Hi.", resp.String())
+	req.Equal(0, resp.TotalTokens())
+	req.Equal(1, len(s.requests))
+}
+
+type failingCache struct{}
+
+func (failingCache) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("cache unavailable")
+}
+
+func (failingCache) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("disk full")
+}
+
+func TestClientCacheErrors(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	s := newFakeServer(t, textResponse("a"), textResponse("b"))
+	cl := newTestClient(t, s)
+	cl.Cache = failingCache{}
+
+	for _, want := range []string{"a", "b"} {
+		resp, err := cl.GenerateText(ctx, NewText("Hello."), nil)
+		req.Nil(err)
+		req.Equal(want, resp.String())
+	}
+}
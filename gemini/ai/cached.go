@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// DefaultMinCacheTokens is the default minimum size of a prefix that is worth caching.
+	DefaultMinCacheTokens = 4096
+	// DefaultCacheContentTTL is the default time to live of cached contents.
+	DefaultCacheContentTTL = time.Hour
+)
+
+// CreateCachedContent caches contents with a system instruction and tools, so that they can be
+// referred to with [WithCachedContent] instead of being sent with each request.
+// Requests with cached contents can't declare tools, so the tools used with them must be cached.
+func (cl *Client) CreateCachedContent(ctx context.Context, contents []*genai.Content, systemInstruction string, tools []*Tool, ttl time.Duration) (*genai.CachedContent, error) {
+	if cl.genai == nil {
+		return nil, fmt.Errorf("cached content: %w", ErrUnsupported)
+	}
+	config := &genai.CreateCachedContentConfig{TTL: ttl, Contents: contents}
+	if systemInstruction != "" {
+		config.SystemInstruction = genai.NewContentFromText(systemInstruction, genai.RoleUser)
+	}
+	for _, t := range tools {
+		config.Tools = append(config.Tools, t.tool())
+	}
+	return cl.genai.Caches.Create(ctx, string(cl.model), config)
+}
+
+// RefreshCachedContent extends the lifetime of cached contents.
+func (cl *Client) RefreshCachedContent(ctx context.Context, name string, ttl time.Duration) (*genai.CachedContent, error) {
+	if cl.genai == nil {
+		return nil, fmt.Errorf("cached content: %w", ErrUnsupported)
+	}
+	return cl.genai.Caches.Update(ctx, name, &genai.UpdateCachedContentConfig{TTL: ttl})
+}
+
+// DeleteCachedContent deletes cached contents.
+func (cl *Client) DeleteCachedContent(ctx context.Context, name string) error {
+	if cl.genai == nil {
+		return fmt.Errorf("cached content: %w", ErrUnsupported)
+	}
+	_, err := cl.genai.Caches.Delete(ctx, name, nil)
+	return err
+}
+
+// PrefixCache sends a shared prefix of contents with each request,
+// caching it if it's large enough for caching to be worth it.
+type PrefixCache struct {
+	// MinTokens is the minimum estimated size of the prefix for it to be cached.
+	// If zero, [DefaultMinCacheTokens] is used.
+	MinTokens int
+	// TTL is the time to live of the cache. It's refreshed when less than half of it remains.
+	// If zero, [DefaultCacheContentTTL] is used.
+	TTL               time.Duration
+	cl                *Client
+	prefix            []*genai.Content
+	systemInstruction string
+	tools             []*Tool
+	mu                sync.Mutex
+	cached            *genai.CachedContent
+	uncached          bool
+}
+
+// NewPrefixCache creates a new prefix cache. The tools are cached with the prefix
+// and must be passed to each request, so that their functions can be called.
+func (cl *Client) NewPrefixCache(prefix []*genai.Content, systemInstruction string, tools []*Tool) *PrefixCache {
+	return &PrefixCache{cl: cl, prefix: prefix, systemInstruction: systemInstruction, tools: tools}
+}
+
+// Prepare returns the contents and options for a request that continues the prefix.
+// If the prefix is cached, only the contents are sent, otherwise they're appended to the prefix.
+func (p *PrefixCache) Prepare(ctx context.Context, in []*genai.Content) ([]*genai.Content, []Option, error) {
+	name, err := p.name(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if name != "" {
+		return in, []Option{WithCachedContent(name)}, nil
+	}
+	var opts []Option
+	if p.systemInstruction != "" {
+		opts = append(opts, WithSystemInstruction(p.systemInstruction))
+	}
+	return append(slices.Clip(p.prefix), in...), opts, nil
+}
+
+// Cached returns the cached content, or nil if the prefix isn't cached.
+func (p *PrefixCache) Cached() *genai.CachedContent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cached
+}
+
+// Close deletes the cache.
+func (p *PrefixCache) Close(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cached == nil {
+		return nil
+	}
+	err := p.cl.DeleteCachedContent(ctx, p.cached.Name)
+	p.cached = nil
+	return err
+}
+
+// name returns the name of the cached content, creating or refreshing it if necessary.
+func (p *PrefixCache) name(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.uncached {
+		return "", nil
+	}
+	ttl := p.TTL
+	if ttl == 0 {
+		ttl = DefaultCacheContentTTL
+	}
+	if p.cached != nil && !p.cached.ExpireTime.IsZero() && !p.cached.ExpireTime.After(time.Now()) {
+		// The cache has expired, so it's created again.
+		p.cached = nil
+	}
+	if p.cached == nil {
+		minTokens := p.MinTokens
+		if minTokens == 0 {
+			minTokens = DefaultMinCacheTokens
+		}
+		if p.cl.genai == nil || EstimateTokens(p.prefix) < minTokens {
+			p.uncached = true
+			return "", nil
+		}
+		cached, err := p.cl.CreateCachedContent(ctx, p.prefix, p.systemInstruction, p.tools, ttl)
+		if err != nil {
+			return "", err
+		}
+		p.cached = cached
+	} else if !p.cached.ExpireTime.IsZero() && time.Until(p.cached.ExpireTime) < ttl/2 {
+		cached, err := p.cl.RefreshCachedContent(ctx, p.cached.Name, ttl)
+		if err != nil {
+			return "", err
+		}
+		p.cached = cached
+	}
+	return p.cached.Name, nil
+}
@@ -0,0 +1,145 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+type cachesServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	calls    []string
+	requests []map[string]any
+	// responses are returned by generateContent before the default response.
+	responses []string
+}
+
+func newCachesServer(t *testing.T) *cachesServer {
+	s := new(cachesServer)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		s.calls = append(s.calls, r.Method+" "+r.URL.Path)
+		s.requests = append(s.requests, req)
+		w.Header().Set("Content-Type", "application/json")
+		// The cache expires soon, so that it's refreshed.
+		expire := time.Now().Add(10 * time.Minute).UTC().Format(time.RFC3339)
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":generateContent") && len(s.responses) > 0:
+			w.Write([]byte(s.responses[0]))
+			s.responses = s.responses[1:]
+		case strings.HasSuffix(r.URL.Path, ":generateContent"):
+			w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Page 3."}]}}],"usageMetadata":{"promptTokenCount":5010,"cachedContentTokenCount":5000,"candidatesTokenCount":2,"totalTokenCount":5012}}`))
+		case r.Method == http.MethodDelete:
+			w.Write([]byte(`{}`))
+		default:
+			fmt.Fprintf(w, `{"name":"cachedContents/abc","model":"models/gemini-3-flash-preview","expireTime":"%s"}`, expire)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestPrefixCache(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	s := newCachesServer(t)
+	cl, err := NewClientWithConfig(ctx, &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: s.URL},
+	}, Gemini3FlashPreview, WithSystemInstruction("Be brief."))
+	req.Nil(err)
+
+	pc := cl.NewPrefixCache(NewText(strings.Repeat("manual ", 100)), "Answer from the manual.", nil)
+	pc.MinTokens = 10
+	for range 2 {
+		in, opts, err := pc.Prepare(ctx, NewText("Where is the index?"))
+		req.Nil(err)
+		req.Equal(1, len(in))
+		resp, err := cl.GenerateText(ctx, in, nil, opts...)
+		req.Nil(err)
+		req.Equal("Page 3.", resp.String())
+		req.Equal(5000, resp.CachedTokens())
+	}
+	req.Equal("cachedContents/abc", pc.Cached().Name)
+	req.Nil(pc.Close(ctx))
+
+	req.Equal([]string{
+		"POST /v1beta/cachedContents",
+		"POST /v1beta/models/gemini-3-flash-preview:generateContent",
+		"PATCH /v1beta/cachedContents/abc",
+		"POST /v1beta/models/gemini-3-flash-preview:generateContent",
+		"DELETE /v1beta/cachedContents/abc",
+	}, s.calls)
+	req.Equal("Answer from the manual.", s.requests[0]["systemInstruction"].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"])
+	req.Equal("cachedContents/abc", s.requests[1]["cachedContent"])
+	req.Nil(s.requests[1]["systemInstruction"])
+}
+
+func TestPrefixCacheSmall(t *testing.T) {
+	req := require.New(t)
+
+	s := newCachesServer(t)
+	cl, err := NewClientWithConfig(context.Background(), &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: s.URL},
+	}, Gemini3FlashPreview)
+	req.Nil(err)
+
+	pc := cl.NewPrefixCache(NewText("A short manual."), "Answer from the manual.", nil)
+	in, opts, err := pc.Prepare(context.Background(), NewText("Where is the index?"))
+	req.Nil(err)
+	req.Equal(2, len(in))
+	req.Equal(1, len(opts))
+	req.Nil(pc.Cached())
+	req.Equal(0, len(s.calls))
+}
+
+func TestPrefixCacheTools(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	s := newCachesServer(t)
+	s.responses = []string{functionCall("lookup", `{"id":"index"}`)}
+	cl, err := NewClientWithConfig(ctx, &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: s.URL},
+	}, Gemini3FlashPreview)
+	req.Nil(err)
+
+	tools := []*Tool{lookupTool(t, map[string]string{"index": "page 3"})}
+	pc := cl.NewPrefixCache(NewText(strings.Repeat("manual ", 100)), "Answer from the manual.", tools)
+	pc.MinTokens = 10
+	in, opts, err := pc.Prepare(ctx, NewText("Where is the index?"))
+	req.Nil(err)
+	resp, err := cl.GenerateText(ctx, in, tools, opts...)
+	req.Nil(err)
+	req.Equal("Page 3.", resp.String())
+	req.Equal(1, len(resp.ToolCalls()))
+
+	decls := s.requests[0]["tools"].([]any)[0].(map[string]any)["functionDeclarations"].([]any)
+	req.Equal("lookup", decls[0].(map[string]any)["name"])
+	for _, r := range s.requests[1:3] {
+		req.Equal("cachedContents/abc", r["cachedContent"])
+		req.Nil(r["tools"])
+		req.Nil(r["toolConfig"])
+	}
+	contents := s.requests[2]["contents"].([]any)
+	req.Equal("page 3", contents[2].(map[string]any)["parts"].([]any)[0].(map[string]any)["functionResponse"].(map[string]any)["response"].(map[string]any)["output"].(map[string]any)["value"])
+}
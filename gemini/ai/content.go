@@ -0,0 +1,183 @@
+package ai
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// DefaultMaxPartSize is the default maximum size of a single inline part.
+	DefaultMaxPartSize = 20 << 20
+	// DefaultMaxInlineSize is the default maximum total size of inline parts.
+	DefaultMaxInlineSize = 20 << 20
+)
+
+// ContentBuilder builds contents with any number of parts and turns.
+// Parts are added to the current user turn. Errors are reported by [ContentBuilder.Build].
+type ContentBuilder struct {
+	// MaxPartSize is the maximum size of a single inline part. If zero, [DefaultMaxPartSize] is used.
+	MaxPartSize int
+	// MaxInlineSize is the maximum total size of inline parts. If zero, [DefaultMaxInlineSize] is used.
+	MaxInlineSize int
+	contents      []*genai.Content
+	inlineSize    int
+	err           error
+}
+
+// NewContent creates a new content builder.
+func NewContent() *ContentBuilder {
+	return new(ContentBuilder)
+}
+
+// Text adds a text part.
+func (b *ContentBuilder) Text(text string) *ContentBuilder {
+	return b.add(genai.RoleUser, genai.NewPartFromText(text))
+}
+
+// Bytes adds an inline part. If the MIME type is empty, it's detected from the data.
+func (b *ContentBuilder) Bytes(data []byte, mimeType string) *ContentBuilder {
+	if mimeType == "" {
+		mimeType = DetectMimeType(data)
+	}
+	return b.inline(data, mimeType)
+}
+
+// Image adds an inline image.
+func (b *ContentBuilder) Image(data []byte) *ContentBuilder {
+	return b.media(data, "image/")
+}
+
+// Audio adds an inline audio.
+func (b *ContentBuilder) Audio(data []byte) *ContentBuilder {
+	return b.media(data, "audio/")
+}
+
+// Video adds an inline video.
+func (b *ContentBuilder) Video(data []byte) *ContentBuilder {
+	return b.media(data, "video/")
+}
+
+// File adds an inline part with the contents of a local file.
+// The MIME type is detected from the file extension or the data.
+func (b *ContentBuilder) File(path string) *ContentBuilder {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return b.fail(err)
+	}
+	mimeType := extMimeType(path)
+	if mimeType == "" {
+		mimeType = DetectMimeType(data)
+	}
+	return b.inline(data, mimeType)
+}
+
+// URI adds a part that refers to a file by its URI, e.g. a file uploaded to the Files API.
+func (b *ContentBuilder) URI(uri, mimeType string) *ContentBuilder {
+	return b.add(genai.RoleUser, genai.NewPartFromURI(uri, mimeType))
+}
+
+// Part adds an arbitrary part.
+func (b *ContentBuilder) Part(part *genai.Part) *ContentBuilder {
+	if part.InlineData != nil && !b.reserve(len(part.InlineData.Data)) {
+		return b
+	}
+	return b.add(genai.RoleUser, part)
+}
+
+// Model adds a model turn with a text. The following parts start a new user turn.
+func (b *ContentBuilder) Model(text string) *ContentBuilder {
+	return b.add(genai.RoleModel, genai.NewPartFromText(text))
+}
+
+// Example adds a few-shot example consisting of a user turn and a model turn.
+func (b *ContentBuilder) Example(input, output string) *ContentBuilder {
+	return b.Text(input).Model(output)
+}
+
+// Build returns the contents or the first error encountered.
+func (b *ContentBuilder) Build() ([]*genai.Content, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.contents) == 0 {
+		return nil, fmt.Errorf("no content")
+	}
+	return b.contents, nil
+}
+
+func (b *ContentBuilder) media(data []byte, prefix string) *ContentBuilder {
+	mimeType := DetectMimeType(data)
+	if !strings.HasPrefix(mimeType, prefix) {
+		return b.fail(fmt.Errorf("data of type '%s' isn't %s", mimeType, strings.TrimSuffix(prefix, "/")))
+	}
+	return b.inline(data, mimeType)
+}
+
+func (b *ContentBuilder) inline(data []byte, mimeType string) *ContentBuilder {
+	if !b.reserve(len(data)) {
+		return b
+	}
+	return b.add(genai.RoleUser, genai.NewPartFromBytes(data, mimeType))
+}
+
+// reserve checks the size limits of an inline part.
+func (b *ContentBuilder) reserve(n int) bool {
+	maxPart := b.MaxPartSize
+	if maxPart == 0 {
+		maxPart = DefaultMaxPartSize
+	}
+	maxInline := b.MaxInlineSize
+	if maxInline == 0 {
+		maxInline = DefaultMaxInlineSize
+	}
+	switch {
+	case n > maxPart:
+		b.fail(fmt.Errorf("inline part of %d bytes exceeds the limit of %d bytes", n, maxPart))
+		return false
+	case b.inlineSize+n > maxInline:
+		b.fail(fmt.Errorf("inline parts of %d bytes exceed the limit of %d bytes", b.inlineSize+n, maxInline))
+		return false
+	}
+	b.inlineSize += n
+	return true
+}
+
+func (b *ContentBuilder) add(role string, part *genai.Part) *ContentBuilder {
+	if n := len(b.contents); n > 0 && b.contents[n-1].Role == role {
+		b.contents[n-1].Parts = append(b.contents[n-1].Parts, part)
+	} else {
+		b.contents = append(b.contents, genai.NewContentFromParts([]*genai.Part{part}, genai.Role(role)))
+	}
+	return b
+}
+
+func (b *ContentBuilder) fail(err error) *ContentBuilder {
+	if b.err == nil {
+		b.err = err
+	}
+	return b
+}
+
+// DetectMimeType detects the MIME type of data. It returns application/octet-stream
+// if the type can't be detected.
+func DetectMimeType(data []byte) string {
+	mimeType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	switch mimeType {
+	case "audio/wave":
+		return "audio/wav"
+	case "application/ogg":
+		return "audio/ogg"
+	}
+	return mimeType
+}
+
+func extMimeType(path string) string {
+	mimeType, _, _ := strings.Cut(mime.TypeByExtension(filepath.Ext(path)), ";")
+	return mimeType
+}
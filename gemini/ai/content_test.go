@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+var (
+	pngData = []byte("\x89PNG\x0D\x0A\x1A\x0A....")
+	pdfData = []byte("%PDF-1.7\n....")
+)
+
+func TestContentBuilder(t *testing.T) {
+	req := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "cv.pdf")
+	req.Nil(os.WriteFile(path, pdfData, 0o644))
+
+	contents, err := NewContent().
+		Example("Classify: cat", "animal").
+		Text("Classify these:").
+		Image(pngData).
+		File(path).
+		Bytes([]byte("plain"), "").
+		URI("https://example.com/files/a", MimeTypePDF).
+		Build()
+	req.Nil(err)
+	req.Equal(3, len(contents))
+	req.Equal(genai.RoleUser, contents[0].Role)
+	req.Equal("Classify: cat", contents[0].Parts[0].Text)
+	req.Equal(genai.RoleModel, contents[1].Role)
+	req.Equal("animal", contents[1].Parts[0].Text)
+	parts := contents[2].Parts
+	req.Equal(5, len(parts))
+	req.Equal(MimeTypeImagePNG, parts[1].InlineData.MIMEType)
+	req.Equal(MimeTypePDF, parts[2].InlineData.MIMEType)
+	req.Equal("text/plain", parts[3].InlineData.MIMEType)
+	req.Equal("https://example.com/files/a", parts[4].FileData.FileURI)
+}
+
+func TestContentBuilderErrors(t *testing.T) {
+	req := require.New(t)
+
+	_, err := NewContent().Image(pdfData).Text("Describe.").Build()
+	req.ErrorContains(err, "isn't image")
+
+	b := &ContentBuilder{MaxPartSize: 20, MaxInlineSize: 20}
+	_, err = b.Bytes(pngData, "").Build()
+	req.Nil(err)
+	_, err = b.Bytes(pngData, "").Build()
+	req.ErrorContains(err, "exceed the limit of 20 bytes")
+
+	b = &ContentBuilder{MaxPartSize: 5}
+	_, err = b.Bytes(pngData, "").Build()
+	req.ErrorContains(err, "exceeds the limit of 5 bytes")
+
+	_, err = NewContent().File("missing.pdf").Build()
+	req.NotNil(err)
+
+	_, err = NewContent().Build()
+	req.NotNil(err)
+}
+
+func TestDetectMimeType(t *testing.T) {
+	req := require.New(t)
+
+	req.Equal(MimeTypePDF, DetectMimeType(pdfData))
+	req.Equal("audio/wav", DetectMimeType([]byte("RIFF\x00\x00\x00\x00WAVEfmt ")))
+	req.Equal("application/octet-stream", DetectMimeType([]byte{0, 1, 2}))
+}
@@ -0,0 +1,56 @@
+package ai
+
+import (
+	"context"
+	"slices"
+
+	"google.golang.org/genai"
+)
+
+// Conversation is a multi-turn conversation that keeps its history,
+// including function calls and responses. It is not safe for concurrent use.
+type Conversation struct {
+	cl      *Client
+	tools   []*Tool
+	options []Option
+	history []*genai.Content
+}
+
+// NewConversation creates a new conversation with the given tools and options.
+func (cl *Client) NewConversation(tools []*Tool, opts ...Option) *Conversation {
+	return &Conversation{cl: cl, tools: tools, options: opts}
+}
+
+// History returns the turns of the conversation.
+func (c *Conversation) History() []*genai.Content {
+	return c.history
+}
+
+// Reset clears the history of the conversation.
+func (c *Conversation) Reset() {
+	c.history = nil
+}
+
+// Send sends a user turn and returns a text response.
+func (c *Conversation) Send(ctx context.Context, parts ...*genai.Part) (*Response, error) {
+	resp, err := c.cl.GenerateText(ctx, c.next(parts), c.tools, c.options...)
+	if err != nil {
+		return nil, err
+	}
+	c.history = resp.Transcript()
+	return resp, nil
+}
+
+// Generate sends a user turn and returns a structured response.
+func (c *Conversation) Generate[T any](ctx context.Context, parts ...*genai.Part) (*T, error) {
+	obj, resp, err := generateStructured[T](ctx, c.cl, c.next(parts), c.tools, c.options)
+	if err != nil {
+		return nil, err
+	}
+	c.history = resp.Transcript()
+	return obj, nil
+}
+
+func (c *Conversation) next(parts []*genai.Part) []*genai.Content {
+	return append(slices.Clone(c.history), genai.NewContentFromParts(parts, genai.RoleUser))
+}
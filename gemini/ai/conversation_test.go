@@ -0,0 +1,39 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+func TestConversation(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t,
+		functionCall("lookup", `{"id":"a"}`),
+		textResponse("It is b."),
+		textResponse(`{\"value\":\"b\"}`),
+	)
+	cl := newTestClient(t, s)
+	conv := cl.NewConversation([]*Tool{lookupTool(t, map[string]string{"a": "b"})})
+
+	resp, err := conv.Send(context.Background(), genai.NewPartFromText("What is a?"))
+	req.Nil(err)
+	req.Equal("It is b.", resp.String())
+	req.Equal(4, len(conv.History()))
+	req.Equal(genai.RoleUser, conv.History()[0].Role)
+	req.NotNil(conv.History()[1].Parts[0].FunctionCall)
+	req.NotNil(conv.History()[2].Parts[0].FunctionResponse)
+	req.Equal(genai.RoleModel, conv.History()[3].Role)
+
+	out, err := conv.Generate[lookupOutput](context.Background(), genai.NewPartFromText("As JSON, please."))
+	req.Nil(err)
+	req.Equal("b", out.Value)
+	req.Equal(6, len(conv.History()))
+	req.Equal(5, len(s.requests[2]["contents"].([]any)))
+
+	conv.Reset()
+	req.Empty(conv.History())
+}
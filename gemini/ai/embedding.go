@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/phomola/ai-go/nlp"
+	"google.golang.org/genai"
+)
+
+var _ nlp.Embedding = (*Embedding)(nil)
+
+// EmbeddingModel specifies an embedding model.
+type EmbeddingModel string
+
+const (
+	// GeminiEmbedding001 represents the Gemini Embedding 001 model.
+	GeminiEmbedding001 EmbeddingModel = "gemini-embedding-001"
+)
+
+// DefaultEmbeddingBatchSize is the default maximum number of texts embedded in a single request.
+const DefaultEmbeddingBatchSize = 100
+
+// Task types of embeddings.
+const (
+	TaskRetrievalQuery     = "RETRIEVAL_QUERY"
+	TaskRetrievalDocument  = "RETRIEVAL_DOCUMENT"
+	TaskSemanticSimilarity = "SEMANTIC_SIMILARITY"
+	TaskClassification     = "CLASSIFICATION"
+	TaskClustering         = "CLUSTERING"
+	TaskQuestionAnswering  = "QUESTION_ANSWERING"
+	TaskFactVerification   = "FACT_VERIFICATION"
+	TaskCodeRetrievalQuery = "CODE_RETRIEVAL_QUERY"
+)
+
+// Embedding is an embedding computed by a Gemini embedding model.
+type Embedding struct {
+	// TaskType is the task the embedding is optimised for, e.g. [TaskRetrievalQuery].
+	// If empty, the model's default is used.
+	TaskType string
+	// Dimensionality is the size of the vectors. If zero, the model's default is used.
+	Dimensionality int
+	// BatchSize is the maximum number of texts embedded in a single request.
+	// If zero, [DefaultEmbeddingBatchSize] is used.
+	BatchSize int
+	// Normalise normalises the vectors to unit length. Vectors of reduced dimensionality
+	// aren't normalised by the model.
+	Normalise bool
+	models    *genai.Models
+	model     EmbeddingModel
+}
+
+// NewEmbedding creates a new Gemini embedding.
+func NewEmbedding(ctx context.Context, model EmbeddingModel) (*Embedding, error) {
+	return NewEmbeddingWithConfig(ctx, nil, model)
+}
+
+// NewEmbeddingWithConfig creates a new Gemini embedding with the given configuration.
+func NewEmbeddingWithConfig(ctx context.Context, config *genai.ClientConfig, model EmbeddingModel) (*Embedding, error) {
+	cl, err := genai.NewClient(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return &Embedding{models: cl.Models, model: model}, nil
+}
+
+// NewEmbedding creates a new embedding that shares the client's connection.
+func (cl *Client) NewEmbedding(model EmbeddingModel) (*Embedding, error) {
+	if cl.genai == nil {
+		return nil, fmt.Errorf("embedding: %w", ErrUnsupported)
+	}
+	return &Embedding{models: cl.genai.Models, model: model}, nil
+}
+
+// WithTaskType returns a copy of the embedding with a different task type,
+// e.g. for embedding queries and documents with the same configuration.
+func (e *Embedding) WithTaskType(taskType string) *Embedding {
+	c := *e
+	c.TaskType = taskType
+	return &c
+}
+
+// Vector returns the vector for the text.
+func (e *Embedding) Vector(text string) (nlp.Vector, error) {
+	vecs, err := e.Vectors(context.Background(), []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vecs[0], nil
+}
+
+// Vectors returns the vectors for the texts, in batches of at most BatchSize texts per request.
+func (e *Embedding) Vectors(ctx context.Context, texts []string) ([]nlp.Vector, error) {
+	batchSize := e.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultEmbeddingBatchSize
+	}
+	config := &genai.EmbedContentConfig{TaskType: e.TaskType}
+	if e.Dimensionality > 0 {
+		config.OutputDimensionality = genai.Ptr(int32(e.Dimensionality))
+	}
+	vecs := make([]nlp.Vector, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		batch := texts[start:min(start+batchSize, len(texts))]
+		contents := make([]*genai.Content, len(batch))
+		for i, text := range batch {
+			contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+		}
+		resp, err := e.models.EmbedContent(ctx, string(e.model), contents, config)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Embeddings) != len(batch) {
+			return nil, fmt.Errorf("%d embeddings returned for %d texts", len(resp.Embeddings), len(batch))
+		}
+		for _, emb := range resp.Embeddings {
+			v := make(nlp.Vector, len(emb.Values))
+			for i, x := range emb.Values {
+				v[i] = float64(x)
+			}
+			if e.Normalise && v.Length() > 0 {
+				v.Normalise()
+			}
+			vecs = append(vecs, v)
+		}
+	}
+	return vecs, nil
+}
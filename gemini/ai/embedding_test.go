@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+type embedRequest struct {
+	Requests []struct {
+		Model                string         `json:"model"`
+		Content              *genai.Content `json:"content"`
+		TaskType             string         `json:"taskType"`
+		OutputDimensionality int            `json:"outputDimensionality"`
+	} `json:"requests"`
+}
+
+func newEmbedServer(t *testing.T, requests *[]embedRequest) *httptest.Server {
+	var mu sync.Mutex
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ":batchEmbedContents") {
+			http.NotFound(w, r)
+			return
+		}
+		var body embedRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		*requests = append(*requests, body)
+		mu.Unlock()
+		var embeddings []string
+		for _, req := range body.Requests {
+			n := float64(len(req.Content.Parts[0].Text))
+			embeddings = append(embeddings, fmt.Sprintf(`{"values":[%g,%g]}`, 3*n, 4*n))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"embeddings":[%s]}`, strings.Join(embeddings, ","))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestEmbedding(t *testing.T) {
+	req := require.New(t)
+
+	var requests []embedRequest
+	s := newEmbedServer(t, &requests)
+	emb, err := NewEmbeddingWithConfig(context.Background(), &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: s.URL},
+	}, GeminiEmbedding001)
+	req.Nil(err)
+	emb.TaskType = TaskRetrievalDocument
+	emb.Dimensionality = 2
+	emb.BatchSize = 2
+
+	vecs, err := emb.Vectors(context.Background(), []string{"a", "bb", "ccc"})
+	req.Nil(err)
+	req.Len(vecs, 3)
+	req.InDeltaSlice([]float64{9, 12}, []float64(vecs[2]), 1e-9)
+	req.Len(requests, 2)
+	req.Len(requests[0].Requests, 2)
+	req.Len(requests[1].Requests, 1)
+	req.Equal("bb", requests[0].Requests[1].Content.Parts[0].Text)
+	req.Equal(TaskRetrievalDocument, requests[0].Requests[0].TaskType)
+	req.Equal(2, requests[0].Requests[0].OutputDimensionality)
+
+	query := emb.WithTaskType(TaskRetrievalQuery)
+	query.Normalise = true
+	v, err := query.Vector("dddd")
+	req.Nil(err)
+	req.InDeltaSlice([]float64{0.6, 0.8}, []float64(v), 1e-9)
+	req.Equal(TaskRetrievalQuery, requests[2].Requests[0].TaskType)
+	req.Equal(TaskRetrievalDocument, emb.TaskType)
+}
+
+func TestEmbeddingUnsupported(t *testing.T) {
+	req := require.New(t)
+
+	_, err := NewClientWithBackend(nil, Gemini3FlashPreview).NewEmbedding(GeminiEmbedding001)
+	req.ErrorIs(err, ErrUnsupported)
+}
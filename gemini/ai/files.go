@@ -0,0 +1,239 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// ErrUnsupported is returned when a feature isn't supported by the client's backend.
+var ErrUnsupported = errors.New("not supported by the backend")
+
+// filePollInterval is the interval in which the state of a processed file is checked.
+var filePollInterval = time.Second
+
+// DefaultExpiryMargin is the default time before the expiration of an uploaded file
+// after which the file isn't reused.
+const DefaultExpiryMargin = 10 * time.Minute
+
+// UploadFile uploads a file to the Files API and waits until it's processed.
+// Uploaded files can be referenced by parts created with [NewFilePart].
+func (cl *Client) UploadFile(ctx context.Context, r io.Reader, mimeType string) (*genai.File, error) {
+	if cl.genai == nil {
+		return nil, fmt.Errorf("files: %w", ErrUnsupported)
+	}
+	f, err := cl.genai.Files.Upload(ctx, r, &genai.UploadFileConfig{MIMEType: mimeType})
+	if err != nil {
+		return nil, err
+	}
+	for f.State == genai.FileStateProcessing {
+		t := time.NewTimer(filePollInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		if f, err = cl.genai.Files.Get(ctx, f.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+	if f.State == genai.FileStateFailed {
+		msg := "processing failed"
+		if f.Error != nil && f.Error.Message != "" {
+			msg = f.Error.Message
+		}
+		return nil, fmt.Errorf("file '%s': %s", f.Name, msg)
+	}
+	return f, nil
+}
+
+// ListFiles lists the uploaded files.
+func (cl *Client) ListFiles(ctx context.Context) iter.Seq2[*genai.File, error] {
+	if cl.genai == nil {
+		return func(yield func(*genai.File, error) bool) {
+			yield(nil, fmt.Errorf("files: %w", ErrUnsupported))
+		}
+	}
+	return cl.genai.Files.All(ctx)
+}
+
+// DeleteFile deletes an uploaded file.
+func (cl *Client) DeleteFile(ctx context.Context, name string) error {
+	if cl.genai == nil {
+		return fmt.Errorf("files: %w", ErrUnsupported)
+	}
+	_, err := cl.genai.Files.Delete(ctx, name, nil)
+	return err
+}
+
+// NewFilePart creates a part that refers to an uploaded file.
+func NewFilePart(f *genai.File) *genai.Part {
+	return genai.NewPartFromURI(f.URI, f.MIMEType)
+}
+
+// UploadedFile adds a part that refers to an uploaded file.
+func (b *ContentBuilder) UploadedFile(f *genai.File) *ContentBuilder {
+	return b.Part(NewFilePart(f))
+}
+
+// FileRegistry uploads files once and reuses them until they expire.
+// Files are identified by the SHA-256 hash of their contents.
+type FileRegistry struct {
+	// ExpiryMargin is the time before the expiration after which a file is uploaded again.
+	// If zero, [DefaultExpiryMargin] is used.
+	ExpiryMargin time.Duration
+	cl           *Client
+	mu           sync.Mutex
+	entries      map[string]*fileEntry
+}
+
+type fileEntry struct {
+	mu   sync.Mutex
+	file *genai.File
+}
+
+// NewFileRegistry creates a new file registry.
+func (cl *Client) NewFileRegistry() *FileRegistry {
+	return &FileRegistry{cl: cl, entries: make(map[string]*fileEntry)}
+}
+
+// Upload returns the uploaded file with the data, uploading it if necessary.
+// If the MIME type is empty, it's detected from the data.
+func (r *FileRegistry) Upload(ctx context.Context, data []byte, mimeType string) (*genai.File, error) {
+	if mimeType == "" {
+		mimeType = DetectMimeType(data)
+	}
+	sum := sha256.Sum256(data)
+	return r.upload(ctx, hex.EncodeToString(sum[:]), func() (io.ReadCloser, string, error) {
+		return io.NopCloser(bytes.NewReader(data)), mimeType, nil
+	})
+}
+
+// UploadPath returns the uploaded file with the contents of a local file, uploading it if necessary.
+// The file is streamed, so it's never read into memory as a whole.
+func (r *FileRegistry) UploadPath(ctx context.Context, path string) (*genai.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	return r.upload(ctx, hex.EncodeToString(h.Sum(nil)), func() (io.ReadCloser, string, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, "", err
+		}
+		mimeType, err := fileMimeType(path, f)
+		if err != nil {
+			f.Close()
+			return nil, "", err
+		}
+		return f, mimeType, nil
+	})
+}
+
+// Files returns the registered files that haven't expired.
+func (r *FileRegistry) Files() []*genai.File {
+	// The entries are locked one by one without holding the registry's lock,
+	// so that an upload in progress doesn't block other uploads.
+	r.mu.Lock()
+	entries := slices.Collect(maps.Values(r.entries))
+	r.mu.Unlock()
+	var files []*genai.File
+	for _, e := range entries {
+		e.mu.Lock()
+		if e.file != nil && !r.expired(e.file) {
+			files = append(files, e.file)
+		}
+		e.mu.Unlock()
+	}
+	return files
+}
+
+// DeleteAll deletes the registered files.
+func (r *FileRegistry) DeleteAll(ctx context.Context) error {
+	r.mu.Lock()
+	entries := r.entries
+	r.entries = make(map[string]*fileEntry)
+	r.mu.Unlock()
+	var errs []error
+	for _, e := range entries {
+		e.mu.Lock()
+		if e.file != nil && !r.expired(e.file) {
+			errs = append(errs, r.cl.DeleteFile(ctx, e.file.Name))
+		}
+		e.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+func (r *FileRegistry) upload(ctx context.Context, key string, open func() (io.ReadCloser, string, error)) (*genai.File, error) {
+	r.mu.Lock()
+	e, ok := r.entries[key]
+	if !ok {
+		e = new(fileEntry)
+		r.entries[key] = e
+	}
+	r.mu.Unlock()
+	// Concurrent uploads of the same data wait for the first one.
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file != nil && !r.expired(e.file) {
+		return e.file, nil
+	}
+	rc, mimeType, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	f, err := r.cl.UploadFile(ctx, rc, mimeType)
+	if err != nil {
+		return nil, err
+	}
+	e.file = f
+	return f, nil
+}
+
+func (r *FileRegistry) expired(f *genai.File) bool {
+	if f.ExpirationTime.IsZero() {
+		return false
+	}
+	margin := r.ExpiryMargin
+	if margin == 0 {
+		margin = DefaultExpiryMargin
+	}
+	return time.Until(f.ExpirationTime) < margin
+}
+
+// fileMimeType returns the MIME type of a file from its extension or its first bytes.
+func fileMimeType(path string, f io.ReadSeeker) (string, error) {
+	if mimeType := extMimeType(path); mimeType != "" {
+		return mimeType, nil
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return DetectMimeType(head[:n]), nil
+}
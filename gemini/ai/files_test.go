@@ -0,0 +1,191 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+type filesServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	uploads []string
+	deleted []string
+	gets    int
+	expiry  time.Time
+	// gate holds uploads of the text/slow type until it's closed.
+	gate chan struct{}
+}
+
+func newFilesServer(t *testing.T) *filesServer {
+	s := &filesServer{expiry: time.Now().Add(48 * time.Hour)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/upload/session" && r.URL.Query().Get("mime") == "text/slow" {
+			<-s.gate
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		file := func(name, mimeType, state string) string {
+			return fmt.Sprintf(`{"name":"files/%s","uri":"%s/v1beta/files/%s","mimeType":"%s","state":"%s","expirationTime":"%s"}`,
+				name, s.URL, name, mimeType, state, s.expiry.Format(time.RFC3339))
+		}
+		switch {
+		case r.URL.Path == "/upload/v1beta/files":
+			w.Header().Set("X-Goog-Upload-Url", s.URL+"/upload/session?mime="+r.Header.Get("X-Goog-Upload-Header-Content-Type"))
+			w.Write([]byte(`{}`))
+		case r.URL.Path == "/upload/session":
+			data, _ := io.ReadAll(r.Body)
+			s.uploads = append(s.uploads, string(data))
+			w.Header().Set("X-Goog-Upload-Status", "final")
+			fmt.Fprintf(w, `{"file":%s}`, file(fmt.Sprint(len(s.uploads)), r.URL.Query().Get("mime"), "PROCESSING"))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1beta/files":
+			fmt.Fprintf(w, `{"files":[%s]}`, file("1", MimeTypePDF, "ACTIVE"))
+		case r.Method == http.MethodGet:
+			s.gets++
+			w.Write([]byte(file(strings.TrimPrefix(r.URL.Path, "/v1beta/files/"), MimeTypePDF, "ACTIVE")))
+		case r.Method == http.MethodDelete:
+			s.deleted = append(s.deleted, strings.TrimPrefix(r.URL.Path, "/v1beta/"))
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newFilesClient(t *testing.T, s *filesServer) *Client {
+	filePollInterval = time.Millisecond
+	cl, err := NewClientWithConfig(context.Background(), &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: s.URL},
+	}, Gemini3FlashPreview)
+	require.Nil(t, err)
+	return cl
+}
+
+func TestUploadFile(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	s := newFilesServer(t)
+	cl := newFilesClient(t, s)
+
+	f, err := cl.UploadFile(ctx, strings.NewReader("%PDF-1.7"), MimeTypePDF)
+	req.Nil(err)
+	req.Equal("files/1", f.Name)
+	req.Equal(genai.FileStateActive, f.State)
+	req.Equal(1, s.gets)
+	req.Equal([]string{"%PDF-1.7"}, s.uploads)
+
+	part := NewFilePart(f)
+	req.Equal(s.URL+"/v1beta/files/1", part.FileData.FileURI)
+	req.Equal(MimeTypePDF, part.FileData.MIMEType)
+
+	var names []string
+	for f, err := range cl.ListFiles(ctx) {
+		req.Nil(err)
+		names = append(names, f.Name)
+	}
+	req.Equal([]string{"files/1"}, names)
+
+	req.Nil(cl.DeleteFile(ctx, "files/1"))
+	req.Equal([]string{"files/1"}, s.deleted)
+}
+
+func TestUploadFileUnsupported(t *testing.T) {
+	cl := NewClientWithBackend(nil, Gemini3FlashPreview)
+	_, err := cl.UploadFile(context.Background(), strings.NewReader(""), MimeTypePDF)
+	require.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestFileRegistry(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	s := newFilesServer(t)
+	cl := newFilesClient(t, s)
+	reg := cl.NewFileRegistry()
+
+	path := filepath.Join(t.TempDir(), "cv.pdf")
+	req.Nil(os.WriteFile(path, []byte("%PDF-1.7 cv"), 0o644))
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			f, err := reg.UploadPath(ctx, path)
+			req.Nil(err)
+			req.Equal("files/1", f.Name)
+		})
+	}
+	wg.Wait()
+	req.Equal(1, len(s.uploads))
+
+	f, err := reg.Upload(ctx, []byte("%PDF-1.7 cv"), "")
+	req.Nil(err)
+	req.Equal("files/1", f.Name)
+	f, err = reg.Upload(ctx, []byte("%PDF-1.7 other"), "")
+	req.Nil(err)
+	req.Equal("files/2", f.Name)
+	req.Equal(2, len(reg.Files()))
+
+	// Files close to their expiration are uploaded again.
+	reg.ExpiryMargin = 72 * time.Hour
+	f, err = reg.UploadPath(ctx, path)
+	req.Nil(err)
+	req.Equal("files/3", f.Name)
+
+	reg.ExpiryMargin = 0
+	req.Nil(reg.DeleteAll(ctx))
+	req.ElementsMatch([]string{"files/2", "files/3"}, s.deleted)
+	req.Equal(0, len(reg.Files()))
+}
+
+func TestFileRegistryConcurrentFiles(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	s := newFilesServer(t)
+	s.gate = make(chan struct{})
+	release := sync.OnceFunc(func() { close(s.gate) })
+	defer release()
+	cl := newFilesClient(t, s)
+	reg := cl.NewFileRegistry()
+
+	slow := make(chan error)
+	go func() {
+		_, err := reg.Upload(ctx, []byte("slow"), "text/slow")
+		slow <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	go reg.Files()
+	time.Sleep(20 * time.Millisecond)
+
+	// Listing the files during a slow upload doesn't block other uploads.
+	fast := make(chan error)
+	go func() {
+		_, err := reg.Upload(ctx, []byte("fast"), "text/plain")
+		fast <- err
+	}()
+	select {
+	case err := <-fast:
+		req.Nil(err)
+	case <-time.After(5 * time.Second):
+		req.Fail("upload blocked")
+	}
+	release()
+	req.Nil(<-slow)
+}
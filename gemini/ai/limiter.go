@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const limiterWindow = time.Minute
+
+// Limiter limits the number of requests and tokens per minute.
+// A limiter can be shared by several clients that use the same quota.
+type Limiter struct {
+	rpm, tpm int
+	mu       sync.Mutex
+	events   []*limiterEvent
+	stats    LimiterStats
+}
+
+type limiterEvent struct {
+	t      time.Time
+	tokens int
+}
+
+// LimiterStats contains the metrics of a limiter.
+type LimiterStats struct {
+	// Requests is the number of admitted requests.
+	Requests int64
+	// Waits is the number of requests that had to wait.
+	Waits int64
+	// TotalWait is the total time spent waiting.
+	TotalWait time.Duration
+	// MaxWait is the longest time a request waited.
+	MaxWait time.Duration
+}
+
+// NewLimiter creates a new limiter. A zero limit means no limit.
+func NewLimiter(requestsPerMinute, tokensPerMinute int) *Limiter {
+	return &Limiter{rpm: requestsPerMinute, tpm: tokensPerMinute}
+}
+
+// Wait blocks until a request with the estimated number of tokens can be made
+// or the context is done.
+func (l *Limiter) Wait(ctx context.Context, tokens int) error {
+	_, err := l.acquire(ctx, tokens)
+	return err
+}
+
+// Stats returns the metrics of the limiter.
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+func (l *Limiter) acquire(ctx context.Context, tokens int) (*limiterEvent, error) {
+	start := time.Now()
+	waited := false
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.prune(now)
+		delay := l.delay(now, tokens)
+		if delay <= 0 {
+			ev := &limiterEvent{t: now, tokens: tokens}
+			l.events = append(l.events, ev)
+			l.stats.Requests++
+			if waited {
+				d := now.Sub(start)
+				l.stats.Waits++
+				l.stats.TotalWait += d
+				l.stats.MaxWait = max(l.stats.MaxWait, d)
+			}
+			l.mu.Unlock()
+			return ev, nil
+		}
+		l.mu.Unlock()
+		waited = true
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// reserve waits until a model call with the contents can be made.
+// The limiter may be nil, in which case it returns immediately.
+func (l *Limiter) reserve(ctx context.Context, contents []*genai.Content) (*limiterEvent, error) {
+	if l == nil {
+		return nil, nil
+	}
+	return l.acquire(ctx, EstimateTokens(contents))
+}
+
+// adjust replaces the estimated number of tokens of a request with the actual one.
+func (l *Limiter) adjust(ev *limiterEvent, usage *genai.GenerateContentResponseUsageMetadata) {
+	if l == nil || ev == nil || usage == nil || usage.TotalTokenCount == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ev.tokens = int(usage.TotalTokenCount)
+}
+
+func (l *Limiter) prune(now time.Time) {
+	i := 0
+	for i < len(l.events) && now.Sub(l.events[i].t) >= limiterWindow {
+		i++
+	}
+	l.events = l.events[i:]
+}
+
+// delay returns how long to wait before a request can be admitted.
+func (l *Limiter) delay(now time.Time, tokens int) time.Duration {
+	var delay time.Duration
+	if l.rpm > 0 && len(l.events) >= l.rpm {
+		delay = l.events[len(l.events)-l.rpm].t.Add(limiterWindow).Sub(now)
+	}
+	if l.tpm > 0 && len(l.events) > 0 {
+		sum := tokens
+		for _, ev := range l.events {
+			sum += ev.tokens
+		}
+		// The oldest requests expire until the tokens fit. A request larger than the limit
+		// is admitted once the window is empty.
+		for _, ev := range l.events {
+			if sum <= l.tpm {
+				break
+			}
+			sum -= ev.tokens
+			delay = max(delay, ev.t.Add(limiterWindow).Sub(now))
+		}
+	}
+	return delay
+}
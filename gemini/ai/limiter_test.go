@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterRequests(t *testing.T) {
+	req := require.New(t)
+
+	l := NewLimiter(2, 0)
+	req.Nil(l.Wait(context.Background(), 10))
+	req.Nil(l.Wait(context.Background(), 10))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req.ErrorIs(l.Wait(ctx, 10), context.DeadlineExceeded)
+	req.Equal(LimiterStats{Requests: 2}, l.Stats())
+}
+
+func TestLimiterTokens(t *testing.T) {
+	req := require.New(t)
+
+	l := NewLimiter(0, 100)
+	now := time.Now()
+	l.events = []*limiterEvent{{t: now.Add(-50 * time.Second), tokens: 60}, {t: now.Add(-10 * time.Second), tokens: 30}}
+	req.Equal(time.Duration(0), l.delay(now, 10))
+	req.Equal(10*time.Second, l.delay(now, 20))
+	req.Equal(50*time.Second, l.delay(now, 80))
+
+	// A request larger than the limit waits for an empty window.
+	req.Equal(50*time.Second, l.delay(now, 200))
+	l.events = nil
+	req.Equal(time.Duration(0), l.delay(now, 200))
+}
+
+func TestLimiterWait(t *testing.T) {
+	req := require.New(t)
+
+	l := NewLimiter(1, 0)
+	l.events = []*limiterEvent{{t: time.Now().Add(-limiterWindow + 20*time.Millisecond)}}
+	req.Nil(l.Wait(context.Background(), 1))
+	stats := l.Stats()
+	req.Equal(int64(1), stats.Requests)
+	req.Equal(int64(1), stats.Waits)
+	req.GreaterOrEqual(stats.TotalWait, 10*time.Millisecond)
+	req.Equal(stats.TotalWait, stats.MaxWait)
+}
+
+func TestLimiterSharedByClients(t *testing.T) {
+	req := require.New(t)
+
+	l := NewLimiter(10, 1000)
+	s := newFakeServer(t, textResponse("One."), textResponse("Two."))
+	cl1, cl2 := newTestClient(t, s), newTestClient(t, s)
+	cl1.Limiter, cl2.Limiter = l, l
+
+	_, err := cl1.GenerateText(context.Background(), NewText("Hi."), nil)
+	req.Nil(err)
+	_, err = cl2.GenerateText(context.Background(), NewText("Hi."), nil)
+	req.Nil(err)
+	req.Equal(int64(2), l.Stats().Requests)
+	req.Equal(2, len(l.events))
+}
@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"context"
+	"iter"
+	"net/http"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"google.golang.org/genai"
+)
+
+var (
+	_ LLM     = (*Client)(nil)
+	_ Backend = (*genai.Models)(nil)
+)
+
+// LLM is a provider-neutral language model that generates text and structured responses
+// and calls tools. If a generation fails after some model calls, the response so far
+// may be returned with the error, so that the usage of the calls is known.
+type LLM interface {
+	GenerateText(context.Context, []*genai.Content, []*Tool, ...Option) (*Response, error)
+	GenerateJSON(context.Context, []*genai.Content, *jsonschema.Schema, []*Tool, ...Option) (*Response, error)
+}
+
+// Backend is an LLM provider used by [Client].
+// The genai types are used as a common representation of contents, configs and responses
+// regardless of the provider's wire format.
+type Backend interface {
+	GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error)
+	GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error]
+}
+
+// HTTPError is an unsuccessful response of an HTTP backend.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       string
+}
+
+func (err *HTTPError) Error() string {
+	if err.Body == "" {
+		return err.Status
+	}
+	return err.Status + ": " + err.Body
+}
@@ -0,0 +1,125 @@
+package ai
+
+import "google.golang.org/genai"
+
+// Option is a generation option.
+// Options passed to [NewClient] are defaults that can be overridden for a single call.
+type Option func(*options)
+
+type options struct {
+	config     genai.GenerateContentConfig
+	noCache    bool
+	maxRepairs *int
+}
+
+// WithSystemInstruction sets the system instruction.
+func WithSystemInstruction(text string) Option {
+	return func(o *options) {
+		o.config.SystemInstruction = genai.NewContentFromText(text, genai.RoleUser)
+	}
+}
+
+// WithTemperature sets the sampling temperature.
+func WithTemperature(temperature float32) Option {
+	return func(o *options) {
+		o.config.Temperature = &temperature
+	}
+}
+
+// WithTopP sets the nucleus sampling probability.
+func WithTopP(topP float32) Option {
+	return func(o *options) {
+		o.config.TopP = &topP
+	}
+}
+
+// WithMaxOutputTokens sets the maximum number of output tokens.
+func WithMaxOutputTokens(n int32) Option {
+	return func(o *options) {
+		o.config.MaxOutputTokens = n
+	}
+}
+
+// WithStopSequences sets the sequences that stop the generation.
+func WithStopSequences(seqs ...string) Option {
+	return func(o *options) {
+		o.config.StopSequences = seqs
+	}
+}
+
+// WithSeed sets the random seed.
+func WithSeed(seed int32) Option {
+	return func(o *options) {
+		o.config.Seed = &seed
+	}
+}
+
+// WithSafetySettings sets the safety settings.
+func WithSafetySettings(settings ...*genai.SafetySetting) Option {
+	return func(o *options) {
+		o.config.SafetySettings = settings
+	}
+}
+
+// WithThinkingBudget sets the number of thinking tokens.
+func WithThinkingBudget(n int32) Option {
+	return func(o *options) {
+		if o.config.ThinkingConfig == nil {
+			o.config.ThinkingConfig = new(genai.ThinkingConfig)
+		}
+		o.config.ThinkingConfig.ThinkingBudget = &n
+	}
+}
+
+func (cl *Client) newOptions(tools []*Tool, opts []Option) *options {
+	o := new(options)
+	for _, opt := range cl.options {
+		opt(o)
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	for _, t := range tools {
+		o.config.Tools = append(o.config.Tools, t.tool())
+	}
+	if o.config.CachedContent != "" {
+		// The system instruction and the tools are part of the cached content.
+		o.config.SystemInstruction = nil
+		o.config.Tools = nil
+		o.config.ToolConfig = nil
+	}
+	return o
+}
+
+// WithThoughts requests thought summaries from the model.
+func WithThoughts() Option {
+	return func(o *options) {
+		if o.config.ThinkingConfig == nil {
+			o.config.ThinkingConfig = new(genai.ThinkingConfig)
+		}
+		o.config.ThinkingConfig.IncludeThoughts = true
+	}
+}
+
+// WithoutCache bypasses the client's response cache.
+func WithoutCache() Option {
+	return func(o *options) {
+		o.noCache = true
+	}
+}
+
+// WithMaxRepairs sets how many times an invalid structured response is sent back
+// to the model to be repaired. If not set, [DefaultMaxRepairs] is used.
+func WithMaxRepairs(n int) Option {
+	return func(o *options) {
+		o.maxRepairs = &n
+	}
+}
+
+// WithCachedContent refers to cached contents created by [Client.CreateCachedContent].
+// The system instruction and the tools aren't sent, since they're part of the cached content.
+func WithCachedContent(name string) Option {
+	return func(o *options) {
+		o.config.CachedContent = name
+	}
+}
@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+func TestOptions(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t, textResponse("a"), textResponse("b"))
+	cl := newTestClient(t, s)
+	cl.options = []Option{
+		WithSystemInstruction("Be brief."),
+		WithTemperature(0.5),
+		WithMaxOutputTokens(100),
+	}
+
+	_, err := cl.GenerateText(context.Background(), NewText("Hi."), nil)
+	req.Nil(err)
+	sys := s.requests[0]["systemInstruction"].(map[string]any)
+	req.Equal([]any{map[string]any{"text": "Be brief."}}, sys["parts"])
+	config := s.requests[0]["generationConfig"].(map[string]any)
+	req.Equal(0.5, config["temperature"])
+	req.Equal(100.0, config["maxOutputTokens"])
+
+	_, err = cl.GenerateText(context.Background(), NewText("Hi."), nil,
+		WithTemperature(0),
+		WithTopP(0.9),
+		WithStopSequences("END"),
+		WithSeed(42),
+		WithThinkingBudget(512),
+		WithSafetySettings(&genai.SafetySetting{Category: genai.HarmCategoryHarassment, Threshold: genai.HarmBlockThresholdBlockNone}),
+	)
+	req.Nil(err)
+	config = s.requests[1]["generationConfig"].(map[string]any)
+	req.Equal(0.0, config["temperature"])
+	req.Equal(0.9, config["topP"])
+	req.Equal(100.0, config["maxOutputTokens"])
+	req.Equal([]any{"END"}, config["stopSequences"])
+	req.Equal(42.0, config["seed"])
+	req.Equal(512.0, config["thinkingConfig"].(map[string]any)["thinkingBudget"])
+	req.Equal(1, len(s.requests[1]["safetySettings"].([]any)))
+}
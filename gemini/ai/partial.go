@@ -0,0 +1,324 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"google.golang.org/genai"
+)
+
+// GenerateStream generates a structured response as a stream of progressively more complete snapshots.
+// The last snapshot is the complete response validated against the schema.
+func (cl *Client) GenerateStream[T any](ctx context.Context, in []*genai.Content, tools []*Tool, opts ...Option) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		sc := newJSONScanner()
+		last := -1
+		for chunk, err := range cl.generateJSONStream[T](ctx, in, tools, opts) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if chunk.Text == "" {
+				continue
+			}
+			sc.write(chunk.Text)
+			// The complete value is yielded only once it has been validated.
+			if sc.end >= 0 || sc.progress() == last {
+				continue
+			}
+			partial, ok := sc.snapshot()
+			if !ok {
+				continue
+			}
+			var obj T
+			if err := json.Unmarshal([]byte(partial), &obj); err != nil {
+				continue
+			}
+			last = sc.progress()
+			if !yield(&obj, nil) {
+				return
+			}
+		}
+		obj, err := decodeStructuredFor[T](sc.text.String())
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		yield(obj, nil)
+	}
+}
+
+// GenerateStreamElements generates a JSON array and streams its elements as soon as they are complete.
+// Each element is validated against the schema before it's yielded.
+func (cl *Client) GenerateStreamElements[E any](ctx context.Context, in []*genai.Content, tools []*Tool, opts ...Option) iter.Seq2[*E, error] {
+	return func(yield func(*E, error) bool) {
+		rs, err := resolveSchemaFor[E]()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		sc := newJSONScanner()
+		n := 0
+		for chunk, err := range cl.generateJSONStream[[]E](ctx, in, tools, opts) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if chunk.Text == "" {
+				continue
+			}
+			sc.write(chunk.Text)
+			for ; n < len(sc.elems); n++ {
+				e, err := decodeJSON[E]([]byte(sc.elems[n]), rs)
+				if err != nil {
+					yield(nil, fmt.Errorf("invalid element %d: %w", n, err))
+					return
+				}
+				if !yield(e, nil) {
+					return
+				}
+			}
+		}
+		elems, err := decodeStructuredFor[[]E](sc.text.String())
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, e := range (*elems)[min(n, len(*elems)):] {
+			if !yield(&e, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (cl *Client) generateJSONStream[T any](ctx context.Context, in []*genai.Content, tools []*Tool, opts []Option) iter.Seq2[*Chunk, error] {
+	schema, err := schemaFor[T]()
+	if err != nil {
+		return func(yield func(*Chunk, error) bool) {
+			yield(nil, err)
+		}
+	}
+	o := cl.newOptions(tools, opts)
+	o.config.ResponseMIMEType = "application/json"
+	o.config.ResponseJsonSchema = schema
+	return cl.generateStream(ctx, in, o, tools)
+}
+
+func decodeStructuredFor[T any](text string) (*T, error) {
+	rs, err := resolveSchemaFor[T]()
+	if err != nil {
+		return nil, err
+	}
+	return decodeStructured[T](text, rs)
+}
+
+func resolveSchemaFor[T any]() (*jsonschema.Resolved, error) {
+	schema, err := schemaFor[T]()
+	if err != nil {
+		return nil, err
+	}
+	return schema.Resolve(nil)
+}
+
+// jsonScanner scans a JSON value streamed in chunks. Each chunk is scanned only once,
+// so that a long response can be snapshotted after every chunk.
+type jsonScanner struct {
+	text      strings.Builder
+	start     int      // start of the value, or -1 if it hasn't been found yet
+	end       int      // end of the value, or -1 if it's incomplete
+	pos       int      // position where the scanning continues
+	invalid   bool     // whether the text isn't JSON
+	stack     []byte   // open containers
+	safe      int      // end of the longest prefix that ends with a complete value
+	safeStack []byte   // open containers of the prefix
+	prev      byte     // previous structural character
+	str       int      // start of an incomplete string, or -1
+	strGood   int      // end of the string's prefix that ends with a complete character
+	key       bool     // whether the incomplete string is a key
+	elemStart int      // start of the current element of a top-level array
+	elems     []string // complete elements of a top-level array
+}
+
+func newJSONScanner() *jsonScanner {
+	return &jsonScanner{start: -1, end: -1, str: -1}
+}
+
+// write scans the next chunk.
+func (sc *jsonScanner) write(chunk string) {
+	sc.text.WriteString(chunk)
+	s := sc.text.String()
+	if sc.invalid || sc.end >= 0 {
+		return
+	}
+	if sc.start < 0 {
+		i := strings.IndexAny(s[sc.pos:], "{[")
+		if i < 0 {
+			sc.pos = len(s)
+			return
+		}
+		sc.start = sc.pos + i
+		sc.pos, sc.safe = sc.start, sc.start
+	}
+	if sc.str >= 0 && !sc.scanString(s) {
+		return
+	}
+	for sc.pos < len(s) {
+		i := sc.pos
+		c := s[i]
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			sc.pos++
+			continue
+		case '{', '[':
+			sc.stack = append(sc.stack, c)
+			sc.mark(i + 1)
+			if len(sc.stack) == 1 {
+				sc.elemStart = i + 1
+			}
+		case '}', ']':
+			if len(sc.stack) == 0 {
+				sc.invalid = true
+				return
+			}
+			sc.stack = sc.stack[:len(sc.stack)-1]
+			if len(sc.stack) == 0 {
+				sc.mark(i + 1)
+				sc.end = i + 1
+				return
+			}
+			sc.value(s, i+1)
+		case ',':
+			if len(sc.stack) == 1 {
+				sc.elemStart = i + 1
+			}
+		case ':':
+		case '"':
+			sc.key = sc.stack[len(sc.stack)-1] == '{' && (sc.prev == '{' || sc.prev == ',')
+			sc.str, sc.strGood = i, i+1
+			sc.prev = c
+			if !sc.scanString(s) {
+				return
+			}
+			continue
+		default:
+			j := i
+			for j < len(s) && strings.IndexByte("+-.0123456789Eaeflnrstu", s[j]) >= 0 {
+				j++
+			}
+			if j == i {
+				sc.invalid = true
+				return
+			}
+			if j == len(s) {
+				// The number or literal may continue.
+				return
+			}
+			sc.value(s, j)
+			sc.prev = c
+			sc.pos = j
+			continue
+		}
+		sc.prev = c
+		sc.pos = i + 1
+	}
+}
+
+// scanString continues scanning an incomplete string. It returns false if the string is still incomplete.
+func (sc *jsonScanner) scanString(s string) bool {
+	end, good := scanString(s, sc.strGood)
+	if end < 0 {
+		sc.strGood = good
+		sc.pos = len(s)
+		return false
+	}
+	if !sc.key {
+		sc.value(s, end+1)
+	}
+	sc.str = -1
+	sc.pos = end + 1
+	return true
+}
+
+func (sc *jsonScanner) mark(i int) {
+	sc.safe = i
+	sc.safeStack = append(sc.safeStack[:0], sc.stack...)
+}
+
+// value marks the end of a value that isn't the top-level one.
+func (sc *jsonScanner) value(s string, end int) {
+	sc.mark(end)
+	if len(sc.stack) == 1 && sc.stack[0] == '[' {
+		sc.elems = append(sc.elems, strings.TrimSpace(s[sc.elemStart:end]))
+	}
+}
+
+// progress returns the length of the text covered by the snapshot.
+func (sc *jsonScanner) progress() int {
+	if sc.str >= 0 && !sc.key {
+		return sc.strGood
+	}
+	return sc.safe
+}
+
+// snapshot closes the truncated JSON value so that it can be decoded.
+// Incomplete keys, numbers and literals are dropped, incomplete strings are kept.
+// It returns false if there is no JSON value yet.
+func (sc *jsonScanner) snapshot() (string, bool) {
+	s := sc.text.String()
+	switch {
+	case sc.invalid || sc.start < 0:
+		return "", false
+	case sc.end >= 0:
+		return s[sc.start:sc.end], true
+	case sc.str >= 0 && !sc.key:
+		return closeJSON(s[sc.start:sc.strGood]+`"`, sc.stack), true
+	default:
+		return closeJSON(s[sc.start:sc.safe], sc.safeStack), true
+	}
+}
+
+// scanString returns the index of the closing quote of a string starting at i,
+// or -1 and the length of the prefix that ends with a complete character.
+func scanString(s string, i int) (int, int) {
+	good := i
+	for i < len(s) {
+		switch s[i] {
+		case '"':
+			return i, good
+		case '\\':
+			if i+1 >= len(s) {
+				return -1, good
+			}
+			n := 2
+			if s[i+1] == 'u' {
+				n = 6
+			}
+			if i+n > len(s) {
+				return -1, good
+			}
+			i += n
+		default:
+			i++
+		}
+		good = i
+	}
+	return -1, good
+}
+
+func closeJSON(prefix string, stack []byte) string {
+	var sb strings.Builder
+	sb.WriteString(strings.TrimRight(prefix, " \t\n\r,"))
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == '{' {
+			sb.WriteByte('}')
+		} else {
+			sb.WriteByte(']')
+		}
+	}
+	return sb.String()
+}
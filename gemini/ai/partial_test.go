@@ -0,0 +1,148 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type entities struct {
+	Items []*person `json:"items"`
+}
+
+func textChunk(text string) string {
+	data, _ := json.Marshal(text)
+	return textResponse(string(data[1 : len(data)-1]))
+}
+
+func completeJSON(text string) (string, bool) {
+	sc := newJSONScanner()
+	sc.write(text)
+	return sc.snapshot()
+}
+
+func TestJSONScanner(t *testing.T) {
+	req := require.New(t)
+
+	for in, out := range map[string]string{
+		`{`:                             `{}`,
+		`{"na`:                          `{}`,
+		`{"name"`:                       `{}`,
+		`{"name":`:                      `{}`,
+		`{"name":"Jo`:                   `{"name":"Jo"}`,
+		`{"name":"J\`:                   `{"name":"J"}`,
+		`{"name":"J\u00`:                `{"name":"J"}`,
+		`{"name":"Jo","age":4`:          `{"name":"Jo"}`,
+		`{"name":"Jo","age":42,`:        `{"name":"Jo","age":42}`,
+		`{"items":[{"name":"a"},{"na`:   `{"items":[{"name":"a"},{}]}`,
+		`{"items":[true, nu`:            `{"items":[true]}`,
+		"```json\n[1, 2, [3":            `[1, 2, []]`,
+		`{"a":{"b":[]}} trailing prose`: `{"a":{"b":[]}}`,
+	} {
+		got, ok := completeJSON(in)
+		req.True(ok, in)
+		req.Equal(out, got, in)
+		req.True(json.Valid([]byte(got)), in)
+	}
+	_, ok := completeJSON("Thinking...")
+	req.False(ok)
+
+	// Scanning byte by byte gives the same snapshots as scanning each prefix at once.
+	text := "Sure:\n" + `{"items":[{"name":"Jo \"J\u00e9\"","age":42},{"name":"b","tags":["x",null]}],"ok":true}`
+	sc := newJSONScanner()
+	for i := range len(text) {
+		sc.write(text[i : i+1])
+		got, ok := sc.snapshot()
+		exp, expOK := completeJSON(text[:i+1])
+		req.Equal(expOK, ok, text[:i+1])
+		req.Equal(exp, got, text[:i+1])
+	}
+	req.Equal(len(text), sc.end)
+}
+
+func TestJSONScannerElements(t *testing.T) {
+	req := require.New(t)
+
+	sc := newJSONScanner()
+	sc.write(`[{"name":"a","age":1}, {"name":"b"`)
+	req.Equal([]string{`{"name":"a","age":1}`}, sc.elems)
+
+	sc = newJSONScanner()
+	sc.write(`[1, 22, 3`)
+	req.Equal([]string{"1", "22"}, sc.elems)
+	sc.write(`]`)
+	req.Equal([]string{"1", "22", "3"}, sc.elems)
+}
+
+func TestGenerateStream(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t, sse(
+		textChunk(`{"items":[{"name":"a","age":1},`),
+		textChunk(`{"name":"b","ag`),
+		textChunk(`e":2}]}`),
+	))
+	cl := newTestClient(t, s)
+
+	var snapshots []*entities
+	for obj, err := range cl.GenerateStream[entities](context.Background(), NewText("Extract."), nil) {
+		req.Nil(err)
+		snapshots = append(snapshots, obj)
+	}
+	// The complete value is yielded only once, after its validation.
+	req.Equal(3, len(snapshots))
+	req.Equal(1, len(snapshots[0].Items))
+	req.Equal(&person{Name: "b"}, snapshots[1].Items[1])
+	req.Equal(&entities{Items: []*person{{Name: "a", Age: 1}, {Name: "b", Age: 2}}}, snapshots[2])
+	req.Equal("application/json", s.requests[0]["generationConfig"].(map[string]any)["responseMimeType"])
+}
+
+func TestGenerateStreamElements(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t, sse(
+		textChunk(`[{"name":"a","age":1},{"name":`),
+		textChunk(`"b","age":2}`),
+		textChunk(`]`),
+	))
+	cl := newTestClient(t, s)
+
+	var elems []*person
+	for e, err := range cl.GenerateStreamElements[person](context.Background(), NewText("Extract."), nil) {
+		req.Nil(err)
+		elems = append(elems, e)
+	}
+	req.Equal([]*person{{Name: "a", Age: 1}, {Name: "b", Age: 2}}, elems)
+}
+
+func TestGenerateStreamElementsInvalid(t *testing.T) {
+	req := require.New(t)
+
+	for invalid, msg := range map[string]string{
+		`{"name":"b","age":-1}`: "invalid element 1: age must not be negative",
+		`{"name":2,"age":1}`:    "invalid element 1: ",
+	} {
+		s := newFakeServer(t, sse(
+			textChunk(`[{"name":"a","age":1},`+invalid+`,`),
+			textChunk(`{"name":"c","age":3}]`),
+		))
+		cl := newTestClient(t, s)
+
+		var (
+			elems []*person
+			errs  []error
+		)
+		for e, err := range cl.GenerateStreamElements[person](context.Background(), NewText("Extract."), nil) {
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			elems = append(elems, e)
+		}
+		req.Equal([]*person{{Name: "a", Age: 1}}, elems, invalid)
+		req.Len(errs, 1, invalid)
+		req.ErrorContains(errs[0], msg, invalid)
+	}
+}
@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// ToolCall is a record of a tool function called during a generation.
+type ToolCall struct {
+	// Step is the number of the tool step, starting at 1.
+	Step     int
+	Name     string
+	Args     map[string]any
+	Output   map[string]any
+	Err      error
+	Duration time.Duration
+}
+
+func addUsage(sum, u *genai.GenerateContentResponseUsageMetadata) {
+	if u == nil {
+		return
+	}
+	sum.PromptTokenCount += u.PromptTokenCount
+	sum.CandidatesTokenCount += u.CandidatesTokenCount
+	sum.ThoughtsTokenCount += u.ThoughtsTokenCount
+	sum.CachedContentTokenCount += u.CachedContentTokenCount
+	sum.ToolUsePromptTokenCount += u.ToolUsePromptTokenCount
+	sum.TotalTokenCount += u.TotalTokenCount
+}
+
+// Raw returns the underlying response of the last model call.
+func (resp *Response) Raw() *genai.GenerateContentResponse {
+	return resp.resp
+}
+
+// Usage returns the usage metadata summed over all model calls.
+func (resp *Response) Usage() *genai.GenerateContentResponseUsageMetadata {
+	return resp.usage
+}
+
+// PromptTokens returns the number of prompt tokens.
+func (resp *Response) PromptTokens() int {
+	return int(resp.usage.PromptTokenCount)
+}
+
+// OutputTokens returns the number of output tokens.
+func (resp *Response) OutputTokens() int {
+	return int(resp.usage.CandidatesTokenCount)
+}
+
+// ThinkingTokens returns the number of thinking tokens.
+func (resp *Response) ThinkingTokens() int {
+	return int(resp.usage.ThoughtsTokenCount)
+}
+
+// CachedTokens returns the number of prompt tokens read from a cache.
+func (resp *Response) CachedTokens() int {
+	return int(resp.usage.CachedContentTokenCount)
+}
+
+// TotalTokens returns the total number of tokens.
+func (resp *Response) TotalTokens() int {
+	return int(resp.usage.TotalTokenCount)
+}
+
+// Candidates returns all candidates of the last model call.
+func (resp *Response) Candidates() []*genai.Candidate {
+	return resp.resp.Candidates
+}
+
+func (resp *Response) candidate() *genai.Candidate {
+	if len(resp.resp.Candidates) == 0 {
+		return new(genai.Candidate)
+	}
+	return resp.resp.Candidates[0]
+}
+
+// FinishReason returns the reason why the model stopped generating.
+func (resp *Response) FinishReason() genai.FinishReason {
+	return resp.candidate().FinishReason
+}
+
+// SafetyRatings returns the safety ratings of the response.
+func (resp *Response) SafetyRatings() []*genai.SafetyRating {
+	return resp.candidate().SafetyRatings
+}
+
+// PromptFeedback returns the safety feedback on the prompt.
+func (resp *Response) PromptFeedback() *genai.GenerateContentResponsePromptFeedback {
+	return resp.resp.PromptFeedback
+}
+
+// Citations returns the sources cited in the response.
+func (resp *Response) Citations() []*genai.Citation {
+	if cm := resp.candidate().CitationMetadata; cm != nil {
+		return cm.Citations
+	}
+	return nil
+}
+
+// GroundingMetadata returns the grounding metadata of the response.
+func (resp *Response) GroundingMetadata() *genai.GroundingMetadata {
+	return resp.candidate().GroundingMetadata
+}
+
+// Thoughts returns the thought summaries of all model turns.
+// Thoughts are only returned by the model if requested with [WithThoughts].
+func (resp *Response) Thoughts() string {
+	var sb strings.Builder
+	for _, c := range resp.transcript {
+		if c.Role != genai.RoleModel {
+			continue
+		}
+		for _, p := range c.Parts {
+			if p.Thought && p.Text != "" {
+				if sb.Len() > 0 {
+					sb.WriteString("\n")
+				}
+				sb.WriteString(p.Text)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// ToolCalls returns the tool functions called during the generation.
+func (resp *Response) ToolCalls() []*ToolCall {
+	return resp.toolCalls
+}
@@ -0,0 +1,162 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// RetryPolicy specifies how failed model calls are retried.
+// Only the model calls are retried, tool functions never run twice.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one.
+	MaxAttempts int
+	// InitialBackoff is the backoff before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff is the upper bound of the backoff.
+	MaxBackoff time.Duration
+	// Multiplier is the factor by which the backoff grows with each attempt.
+	Multiplier float64
+	// Retryable reports whether an error is retryable. If nil, [IsRetryable] is used.
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy is a retry policy suitable for most uses.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+}
+
+// IsRetryable reports whether an error is transient, i.e. a rate limit,
+// a server error or a network error.
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if code, ok := statusCode(err); ok {
+		switch code {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+			http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func statusCode(err error) (int, bool) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode, true
+	}
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code, true
+	}
+	var apiErrPtr *genai.APIError
+	if errors.As(err, &apiErrPtr) {
+		return apiErrPtr.Code, true
+	}
+	return 0, false
+}
+
+// RetryAfter returns the delay requested by the server, either in the Retry-After header
+// or in the RetryInfo details of a Gemini error. It returns zero if there is none.
+func RetryAfter(err error) time.Duration {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.Header != nil {
+		if v := httpErr.Header.Get("Retry-After"); v != "" {
+			if s, err := strconv.Atoi(v); err == nil {
+				return time.Duration(s) * time.Second
+			}
+			if t, err := http.ParseTime(v); err == nil {
+				return max(time.Until(t), 0)
+			}
+		}
+		return 0
+	}
+	var details []map[string]any
+	var apiErr genai.APIError
+	var apiErrPtr *genai.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		details = apiErr.Details
+	case errors.As(err, &apiErrPtr):
+		details = apiErrPtr.Details
+	}
+	for _, d := range details {
+		if d["@type"] != "type.googleapis.com/google.rpc.RetryInfo" {
+			continue
+		}
+		if s, ok := d["retryDelay"].(string); ok {
+			if delay, err := time.ParseDuration(s); err == nil {
+				return delay
+			}
+		}
+	}
+	return 0
+}
+
+func (p *RetryPolicy) backoff(attempt int, err error) time.Duration {
+	if d := RetryAfter(err); d > 0 {
+		return d
+	}
+	d := float64(p.InitialBackoff)
+	for range attempt - 1 {
+		d *= max(p.Multiplier, 1)
+	}
+	if p.MaxBackoff > 0 {
+		d = min(d, float64(p.MaxBackoff))
+	}
+	return time.Duration(d/2 + rand.Float64()*d/2)
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// permanentError is an error that must not be retried regardless of the policy.
+type permanentError struct {
+	err error
+}
+
+func (err *permanentError) Error() string {
+	return err.err.Error()
+}
+
+// retry calls f until it succeeds, returns a fatal error or the attempts are exhausted.
+// The policy may be nil, in which case f is called only once.
+func (p *RetryPolicy) retry(ctx context.Context, f func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if perr, ok := err.(*permanentError); ok {
+			return perr.err
+		}
+		if err == nil || p == nil || attempt >= p.MaxAttempts || !p.retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		t := time.NewTimer(p.backoff(attempt, err))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
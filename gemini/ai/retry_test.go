@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+func TestRetry(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t,
+		errorResponse(http.StatusServiceUnavailable, `{"error":{"code":503,"message":"overloaded","status":"UNAVAILABLE"}}`),
+		functionCall("lookup", `{"id":"a"}`),
+		errorResponse(http.StatusTooManyRequests, `{"error":{"code":429,"message":"quota","status":"RESOURCE_EXHAUSTED","details":[{"@type":"type.googleapis.com/google.rpc.RetryInfo","retryDelay":"0.02s"}]}}`),
+		textResponse("It is b."),
+	)
+	cl := newTestClient(t, s)
+	cl.RetryPolicy = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 2}
+
+	calls := 0
+	var tool Tool
+	req.Nil(AddFunction(&tool, "lookup", "Looks up a value.", func(_ context.Context, in *lookupInput) (*lookupOutput, error) {
+		calls++
+		return &lookupOutput{Value: "b"}, nil
+	}))
+
+	start := time.Now()
+	resp, err := cl.GenerateText(context.Background(), NewText("What is a?"), []*Tool{&tool})
+	req.Nil(err)
+	req.Equal("It is b.", resp.String())
+	req.Equal(1, calls)
+	req.Equal(4, len(s.requests))
+	req.GreaterOrEqual(time.Since(start), 20*time.Millisecond)
+}
+
+func TestRetryFatal(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t,
+		errorResponse(http.StatusBadRequest, `{"error":{"code":400,"message":"bad request","status":"INVALID_ARGUMENT"}}`),
+		textResponse("unreachable"),
+	)
+	cl := newTestClient(t, s)
+	cl.RetryPolicy = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+	_, err := cl.GenerateText(context.Background(), NewText("Hi."), nil)
+	var apiErr genai.APIError
+	req.ErrorAs(err, &apiErr)
+	req.Equal(http.StatusBadRequest, apiErr.Code)
+	req.Equal(1, len(s.requests))
+}
+
+func TestRetryExhausted(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t,
+		errorResponse(http.StatusInternalServerError, `{"error":{"code":500,"message":"internal"}}`),
+		errorResponse(http.StatusInternalServerError, `{"error":{"code":500,"message":"internal"}}`),
+		textResponse("unreachable"),
+	)
+	cl := newTestClient(t, s)
+	cl.RetryPolicy = &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+
+	_, err := cl.GenerateText(context.Background(), NewText("Hi."), nil)
+	req.NotNil(err)
+	req.Equal(2, len(s.requests))
+}
+
+func TestRetryStream(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t,
+		errorResponse(http.StatusServiceUnavailable, `{"error":{"code":503,"message":"overloaded"}}`),
+		sse(textResponse("Hello.")),
+	)
+	cl := newTestClient(t, s)
+	cl.RetryPolicy = &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+
+	var text string
+	for chunk, err := range cl.GenerateTextStream(context.Background(), NewText("Hi."), nil) {
+		req.Nil(err)
+		text += chunk.Text
+	}
+	req.Equal("Hello.", text)
+	req.Equal(2, len(s.requests))
+}
+
+func TestRetryClassification(t *testing.T) {
+	req := require.New(t)
+
+	req.True(IsRetryable(&HTTPError{StatusCode: http.StatusTooManyRequests}))
+	req.True(IsRetryable(genai.APIError{Code: http.StatusBadGateway}))
+	req.False(IsRetryable(&HTTPError{StatusCode: http.StatusUnauthorized}))
+	req.False(IsRetryable(context.Canceled))
+	req.False(IsRetryable(errors.New("other")))
+
+	req.Equal(7*time.Second, RetryAfter(&HTTPError{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"7"}}}))
+	req.Equal(time.Duration(0), RetryAfter(&HTTPError{StatusCode: http.StatusTooManyRequests}))
+	req.Equal(1500*time.Millisecond, RetryAfter(genai.APIError{Details: []map[string]any{{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "1.5s"}}}))
+
+	p := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 10}
+	req.LessOrEqual(p.backoff(1, nil), 100*time.Millisecond)
+	req.GreaterOrEqual(p.backoff(3, nil), 500*time.Millisecond)
+	req.LessOrEqual(p.backoff(3, nil), time.Second)
+}
@@ -1,7 +1,203 @@
 package ai
 
-import "github.com/google/jsonschema-go/jsonschema"
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// JSONSchemaer is implemented by types that provide their own JSON schema.
+// The method is called on the zero value of the type.
+type JSONSchemaer interface {
+	JSONSchema() *jsonschema.Schema
+}
+
+var jsonSchemaerType = reflect.TypeFor[JSONSchemaer]()
 
 func schemaFor[T any]() (*jsonschema.Schema, error) {
-	return jsonschema.For[T](nil)
+	return SchemaForType(reflect.TypeFor[T]())
+}
+
+// SchemaForType returns the JSON schema of a type.
+// In addition to the jsonschema tag with the description, the following struct tags are honoured:
+//
+//   - enum: comma-separated allowed values
+//   - minimum, maximum: bounds of numbers
+//   - pattern: regular expression for strings
+//   - format: format of strings, e.g. date-time or uuid
+//   - example: an example value
+//
+// For slices, the tags apply to the elements. Types that implement [JSONSchemaer] provide their own schemas.
+func SchemaForType(t reflect.Type) (*jsonschema.Schema, error) {
+	types := make(map[reflect.Type]*jsonschema.Schema)
+	collectSchemaers(t, types, make(map[reflect.Type]bool))
+	s, err := jsonschema.ForType(t, &jsonschema.ForOptions{TypeSchemas: types})
+	if err != nil {
+		return nil, err
+	}
+	if err := applyTags(t, s, types, make(map[reflect.Type]bool)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// collectSchemaers finds the types that implement JSONSchemaer.
+func collectSchemaers(t reflect.Type, types map[reflect.Type]*jsonschema.Schema, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Only concrete types provide schemas, the zero value of an interface is nil.
+	if seen[t] || t.Kind() == reflect.Interface {
+		return
+	}
+	seen[t] = true
+	switch {
+	case t.Implements(jsonSchemaerType):
+		types[t] = reflect.Zero(t).Interface().(JSONSchemaer).JSONSchema()
+		return
+	case reflect.PointerTo(t).Implements(jsonSchemaerType):
+		types[t] = reflect.New(t).Interface().(JSONSchemaer).JSONSchema()
+		return
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		collectSchemaers(t.Elem(), types, seen)
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if f := t.Field(i); f.IsExported() || f.Anonymous {
+				collectSchemaers(f.Type, types, seen)
+			}
+		}
+	}
+}
+
+// applyTags applies the struct tags of the type's fields to the schema.
+func applyTags(t reflect.Type, s *jsonschema.Schema, types map[reflect.Type]*jsonschema.Schema, seen map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if s == nil || types[t] != nil || seen[t] {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return applyTags(t.Elem(), s.Items, types, seen)
+	case reflect.Map:
+		return applyTags(t.Elem(), s.AdditionalProperties, types, seen)
+	case reflect.Struct:
+	default:
+		return nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, ok := jsonName(f)
+		if !ok {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			// The fields of an embedded struct are promoted.
+			if err := applyTags(f.Type, s, types, seen); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := s.Properties[name]
+		if prop == nil {
+			continue
+		}
+		if err := applyTags(f.Type, prop, types, seen); err != nil {
+			return err
+		}
+		target := prop
+		if k := derefType(f.Type).Kind(); (k == reflect.Slice || k == reflect.Array) && prop.Items != nil {
+			target = prop.Items
+		}
+		if err := applyFieldTags(f, target); err != nil {
+			return fmt.Errorf("field '%s': %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+func applyFieldTags(f reflect.StructField, s *jsonschema.Schema) error {
+	if v, ok := f.Tag.Lookup("enum"); ok {
+		for _, item := range strings.Split(v, ",") {
+			value, err := parseValue(s, strings.TrimSpace(item))
+			if err != nil {
+				return err
+			}
+			s.Enum = append(s.Enum, value)
+		}
+	}
+	for tag, dst := range map[string]**float64{"minimum": &s.Minimum, "maximum": &s.Maximum} {
+		if v, ok := f.Tag.Lookup(tag); ok {
+			x, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %w", tag, err)
+			}
+			*dst = &x
+		}
+	}
+	if v, ok := f.Tag.Lookup("pattern"); ok {
+		s.Pattern = v
+	}
+	if v, ok := f.Tag.Lookup("format"); ok {
+		s.Format = v
+	}
+	if v, ok := f.Tag.Lookup("example"); ok {
+		value, err := parseValue(s, v)
+		if err != nil {
+			return err
+		}
+		s.Examples = append(s.Examples, value)
+	}
+	return nil
+}
+
+// parseValue parses a tag value according to the type of the schema.
+func parseValue(s *jsonschema.Schema, v string) (any, error) {
+	typ := s.Type
+	for _, t := range s.Types {
+		if t != "null" {
+			typ = t
+		}
+	}
+	switch typ {
+	case "integer":
+		return strconv.ParseInt(v, 10, 64)
+	case "number":
+		return strconv.ParseFloat(v, 64)
+	case "boolean":
+		return strconv.ParseBool(v)
+	}
+	return v, nil
+}
+
+// jsonName returns the JSON name of a field and whether it's encoded.
+// The name is empty if the tag doesn't specify it.
+func jsonName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() && !f.Anonymous {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, true
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
 }
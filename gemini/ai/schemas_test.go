@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/stretchr/testify/require"
+)
+
+type orderID [16]byte
+
+func (orderID) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{Type: "string", Format: "uuid"}
+}
+
+type address struct {
+	City string `json:"city" pattern:"^[A-Z]" example:"Prague"`
+}
+
+type audit struct {
+	Created string `json:"created" format:"date-time"`
+}
+
+type order struct {
+	audit
+	ID       orderID  `json:"id"`
+	Status   string   `json:"status" enum:"open, closed"`
+	Priority int      `json:"priority" enum:"1,2,3"`
+	Quantity float64  `json:"quantity" minimum:"0" maximum:"100"`
+	Tags     []string `json:"tags" enum:"a,b"`
+	Address  *address `json:"address,omitempty" jsonschema:"The delivery address."`
+}
+
+func TestSchemaTags(t *testing.T) {
+	req := require.New(t)
+
+	s, err := schemaFor[order]()
+	req.Nil(err)
+	req.Equal([]any{"open", "closed"}, s.Properties["status"].Enum)
+	req.Equal([]any{int64(1), int64(2), int64(3)}, s.Properties["priority"].Enum)
+	req.Equal(0.0, *s.Properties["quantity"].Minimum)
+	req.Equal(100.0, *s.Properties["quantity"].Maximum)
+	req.Equal([]any{"a", "b"}, s.Properties["tags"].Items.Enum)
+	req.Equal("uuid", s.Properties["id"].Format)
+	req.Equal("date-time", s.Properties["created"].Format)
+	addr := s.Properties["address"]
+	req.Equal("The delivery address.", addr.Description)
+	req.Equal("^[A-Z]", addr.Properties["city"].Pattern)
+	req.Equal([]any{"Prague"}, addr.Properties["city"].Examples)
+
+	rs, err := s.Resolve(nil)
+	req.Nil(err)
+	valid := map[string]any{"created": "2026-01-01T00:00:00Z", "id": "x", "status": "open", "priority": 2.0, "quantity": 5.0, "tags": []any{"a"}}
+	req.Nil(rs.Validate(valid))
+	for key, value := range map[string]any{"status": "lost", "priority": 4.0, "quantity": 101.0, "tags": []any{"c"}} {
+		invalid := map[string]any{}
+		for k, v := range valid {
+			invalid[k] = v
+		}
+		invalid[key] = value
+		req.NotNil(rs.Validate(invalid), key)
+	}
+}
+
+func TestSchemaTagsInvalid(t *testing.T) {
+	_, err := schemaFor[struct {
+		N int `json:"n" minimum:"zero"`
+	}]()
+	require.ErrorContains(t, err, "minimum")
+}
+
+func TestSchemaInterfaceField(t *testing.T) {
+	req := require.New(t)
+
+	s, err := schemaFor[struct {
+		S JSONSchemaer `json:"s"`
+	}]()
+	req.Nil(err)
+	req.NotNil(s.Properties["s"])
+}
+
+func TestAddFunctionSchemaTags(t *testing.T) {
+	req := require.New(t)
+
+	var tool Tool
+	req.Nil(AddFunction(&tool, "set", "Sets the status.", func(_ context.Context, in *struct {
+		Status string `json:"status" enum:"open,closed"`
+	}) (*lookupOutput, error) {
+		return &lookupOutput{Value: in.Status}, nil
+	}))
+	_, err := tool.Functions["set"](context.Background(), map[string]any{"status": "lost"})
+	req.ErrorContains(err, "invalid arguments")
+	out, err := tool.Functions["set"](context.Background(), map[string]any{"status": "open"})
+	req.Nil(err)
+	req.Equal(map[string]any{"value": "open"}, out)
+}
@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+
+	"google.golang.org/genai"
+)
+
+// Chunk is a part of a streamed response.
+type Chunk struct {
+	// Text is a text delta.
+	Text string
+	// FunctionCall is a function call requested by the model.
+	FunctionCall *genai.FunctionCall
+	// FunctionResponse is the response of an executed function call.
+	FunctionResponse *genai.FunctionResponse
+	// Usage is the usage metadata summed over all model calls. It is only set in the final chunk.
+	Usage *genai.GenerateContentResponseUsageMetadata
+}
+
+// GenerateTextStream generates a text response as a stream of chunks.
+// Function calls requested by the model are executed before the stream continues.
+func (cl *Client) GenerateTextStream(ctx context.Context, in []*genai.Content, tools []*Tool, opts ...Option) iter.Seq2[*Chunk, error] {
+	return cl.generateStream(ctx, in, cl.newOptions(tools, opts), tools)
+}
+
+func (cl *Client) generateStream(ctx context.Context, in []*genai.Content, o *options, tools []*Tool) iter.Seq2[*Chunk, error] {
+	return func(yield func(*Chunk, error) bool) {
+		a := cl.newAgent(tools)
+		transcript := slices.Clone(in)
+		usage := new(genai.GenerateContentResponseUsageMetadata)
+		for step := 1; ; step++ {
+			var (
+				parts     []*genai.Part
+				calls     []*genai.FunctionCall
+				stepUsage *genai.GenerateContentResponseUsageMetadata
+			)
+			stopped := false
+			var err error
+			if transcript, err = cl.truncate(ctx, transcript); err != nil {
+				yield(nil, err)
+				return
+			}
+			if err := cl.RetryPolicy.retry(ctx, func() error {
+				parts, calls, stepUsage = nil, nil, nil
+				ev, err := cl.Limiter.reserve(ctx, transcript)
+				if err != nil {
+					return err
+				}
+				defer func() { cl.Limiter.adjust(ev, stepUsage) }()
+				for resp, err := range cl.backend.GenerateContentStream(ctx, string(cl.model), transcript, &o.config) {
+					if err != nil {
+						if len(parts) > 0 {
+							// Chunks have already been yielded, so the step can't be repeated.
+							return &permanentError{err}
+						}
+						return err
+					}
+					if resp.UsageMetadata != nil {
+						stepUsage = resp.UsageMetadata
+					}
+					if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+						continue
+					}
+					for _, part := range resp.Candidates[0].Content.Parts {
+						parts = append(parts, part)
+						switch {
+						case part.FunctionCall != nil:
+							calls = append(calls, part.FunctionCall)
+							if !yield(&Chunk{FunctionCall: part.FunctionCall}, nil) {
+								stopped = true
+								return nil
+							}
+						case part.Text != "" && !part.Thought:
+							if !yield(&Chunk{Text: part.Text}, nil) {
+								stopped = true
+								return nil
+							}
+						}
+					}
+				}
+				return nil
+			}); err != nil {
+				yield(nil, err)
+				return
+			}
+			if stopped {
+				return
+			}
+			addUsage(usage, stepUsage)
+			transcript = append(transcript, genai.NewContentFromParts(parts, genai.RoleModel))
+			if len(calls) == 0 {
+				yield(&Chunk{Usage: usage}, nil)
+				return
+			}
+			if step >= a.maxSteps {
+				yield(nil, fmt.Errorf("%w (%d)", ErrMaxSteps, a.maxSteps))
+				return
+			}
+			content, err := a.runTools(ctx, calls)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, part := range content.Parts {
+				if !yield(&Chunk{FunctionResponse: part.FunctionResponse}, nil) {
+					return
+				}
+			}
+			transcript = append(transcript, content)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateTextStream(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t,
+		sse(functionCall("lookup", `{"id":"a"}`)),
+		sse(
+			textResponse("The value "),
+			textResponse("is b."),
+			`{"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":4,"totalTokenCount":14}}`,
+		),
+	)
+	cl := newTestClient(t, s)
+
+	var (
+		text  strings.Builder
+		calls int
+		resps int
+		total int32
+	)
+	for chunk, err := range cl.GenerateTextStream(context.Background(), NewText("Look up a."), []*Tool{lookupTool(t, map[string]string{"a": "b"})}) {
+		req.Nil(err)
+		text.WriteString(chunk.Text)
+		if chunk.FunctionCall != nil {
+			calls++
+			req.Equal("lookup", chunk.FunctionCall.Name)
+		}
+		if chunk.FunctionResponse != nil {
+			resps++
+			req.Equal(map[string]any{"output": map[string]any{"value": "b"}}, chunk.FunctionResponse.Response)
+		}
+		if chunk.Usage != nil {
+			total = chunk.Usage.TotalTokenCount
+		}
+	}
+	req.Equal("The value is b.", text.String())
+	req.Equal(1, calls)
+	req.Equal(1, resps)
+	req.Equal(int32(14), total)
+	req.Equal(3, len(s.requests[1]["contents"].([]any)))
+}
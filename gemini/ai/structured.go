@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"google.golang.org/genai"
+)
+
+// DefaultMaxRepairs is the default number of times an invalid structured response is repaired.
+const DefaultMaxRepairs = 2
+
+// Validator is implemented by structured outputs that check their own values.
+type Validator interface {
+	Validate() error
+}
+
+// maxRepairs returns the number of repairs set by the options, including the client defaults.
+func maxRepairs(llm LLM, opts []Option) int {
+	o := new(options)
+	if cl, ok := llm.(*Client); ok {
+		for _, opt := range cl.options {
+			opt(o)
+		}
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.maxRepairs == nil {
+		return DefaultMaxRepairs
+	}
+	return *o.maxRepairs
+}
+
+// decodeStructured extracts the JSON value from a response and decodes it with [decodeJSON].
+func decodeStructured[T any](text string, rs *jsonschema.Resolved) (*T, error) {
+	data, err := extractJSON(text, jsonStart(rs.Schema()))
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON[T](data, rs)
+}
+
+// decodeJSON decodes a JSON value, validates it against the schema
+// and calls its Validate method if it has one.
+func decodeJSON[T any](data []byte, rs *jsonschema.Resolved) (*T, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	if err := rs.Validate(v); err != nil {
+		return nil, err
+	}
+	var obj T
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	if v, ok := any(&obj).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &obj, nil
+}
+
+// extractJSON returns the first JSON value in the text that starts with one of the characters,
+// ignoring markdown code fences and any prose around the value.
+func extractJSON(text, start string) ([]byte, error) {
+	var firstErr error
+	for i := 0; ; i++ {
+		n := strings.IndexAny(text[i:], start)
+		if n < 0 {
+			break
+		}
+		i += n
+		// Brackets in the prose, such as "see [1]", don't start the value, so the later ones are tried.
+		var raw json.RawMessage
+		err := json.NewDecoder(strings.NewReader(text[i:])).Decode(&raw)
+		if err == nil {
+			return bytes.TrimSpace(raw), nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = errors.New("no JSON value in response")
+	}
+	return nil, firstErr
+}
+
+// jsonStart returns the characters that can start a JSON value of the schema.
+func jsonStart(s *jsonschema.Schema) string {
+	switch s.Type {
+	case "object":
+		return "{"
+	case "array":
+		return "["
+	default:
+		return "{["
+	}
+}
+
+// repairPrompt returns the prompt asking the model to fix an invalid response.
+func repairPrompt(err error) *genai.Content {
+	return genai.NewContentFromText(fmt.Sprintf("The response is invalid: %v\nRespond again with only the corrected JSON.", err), genai.RoleUser)
+}
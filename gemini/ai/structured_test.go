@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type person struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func (p *person) Validate() error {
+	if p.Age < 0 {
+		return errors.New("age must not be negative")
+	}
+	return nil
+}
+
+func TestExtractJSON(t *testing.T) {
+	req := require.New(t)
+
+	for _, text := range []string{
+		`{"name":"a"}`,
+		"```json\n{\"name\":\"a\"}\n```",
+		"Here it is:\n```\n{\"name\":\"a\"}\n```\nHope this helps.",
+		`{"name":"a"} I extracted the name.`,
+	} {
+		data, err := extractJSON(text, "{[")
+		req.Nil(err)
+		req.Equal(`{"name":"a"}`, string(data))
+	}
+	for _, text := range []string{
+		"{\n  \"name\": \"Use ```code``` fences.\"\n}",
+		"```json\n{\"name\": \"Use ```code``` fences.\"}\n```",
+	} {
+		data, err := extractJSON(text, "{[")
+		req.Nil(err)
+		req.JSONEq(`{"name":"Use `+"```code```"+` fences."}`, string(data))
+	}
+	data, err := extractJSON("Result [see below]:\n```json\n{\"name\":\"a\"}\n```", "{[")
+	req.Nil(err)
+	req.Equal(`{"name":"a"}`, string(data))
+	data, err = extractJSON("As noted in [source], the list is [1, 2].", "[")
+	req.Nil(err)
+	req.Equal(`[1, 2]`, string(data))
+	_, err = extractJSON("No JSON here.", "{[")
+	req.NotNil(err)
+
+	// A bracket in the prose isn't taken for an object.
+	p, err := decodeStructuredFor[person]("See [1]: {\"name\":\"a\",\"age\":3}")
+	req.Nil(err)
+	req.Equal(&person{Name: "a", Age: 3}, p)
+}
+
+func TestGenerateRepair(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t,
+		textResponse(`{\"name\":\"a\"}`),
+		textResponse(`{\"name\":\"a\",\"age\":-1}`),
+		textResponse("```json\\n{\\\"name\\\":\\\"a\\\",\\\"age\\\":3}\\n```"),
+	)
+	cl := newTestClient(t, s)
+
+	out, err := Generate[person](context.Background(), cl, NewText("Extract."), nil)
+	req.Nil(err)
+	req.Equal(&person{Name: "a", Age: 3}, out)
+	req.Equal(3, len(s.requests))
+
+	contents := s.requests[1]["contents"].([]any)
+	req.Equal(3, len(contents))
+	req.Contains(contents[2].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"], "missing properties")
+	contents = s.requests[2]["contents"].([]any)
+	req.Equal(5, len(contents))
+	req.Contains(contents[4].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"], "age must not be negative")
+}
+
+func TestGenerateRepairExhausted(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t, textResponse(`{\"name\":\"a\"}`))
+	cl := newTestClient(t, s)
+
+	_, err := Generate[person](context.Background(), cl, NewText("Extract."), nil, WithMaxRepairs(0))
+	req.ErrorContains(err, "invalid response")
+	req.Equal(1, len(s.requests))
+}
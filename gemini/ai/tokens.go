@@ -0,0 +1,203 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+)
+
+// TokenCounter is implemented by backends that count tokens.
+type TokenCounter interface {
+	CountTokens(ctx context.Context, model string, contents []*genai.Content, config *genai.CountTokensConfig) (*genai.CountTokensResponse, error)
+}
+
+var _ TokenCounter = (*genai.Models)(nil)
+
+// CountTokens counts the tokens of contents. If the backend doesn't count tokens,
+// the number is estimated by [EstimateTokens].
+func (cl *Client) CountTokens(ctx context.Context, contents []*genai.Content) (int, error) {
+	tc, ok := cl.backend.(TokenCounter)
+	if !ok {
+		return EstimateTokens(contents), nil
+	}
+	resp, err := tc.CountTokens(ctx, string(cl.model), contents, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.TotalTokens), nil
+}
+
+// EstimateTokens roughly estimates the number of tokens of contents without calling the model.
+func EstimateTokens(contents []*genai.Content) int {
+	n := 0
+	for _, c := range contents {
+		for _, p := range c.Parts {
+			switch {
+			case p.InlineData != nil || p.FileData != nil:
+				n += 258
+			case p.FunctionCall != nil:
+				n += estimateJSONTokens(p.FunctionCall.Name, p.FunctionCall.Args)
+			case p.FunctionResponse != nil:
+				n += estimateJSONTokens(p.FunctionResponse.Name, p.FunctionResponse.Response)
+			default:
+				n += (utf8.RuneCountInString(p.Text) + 3) / 4
+			}
+		}
+	}
+	return n
+}
+
+// estimateJSONTokens estimates the tokens of a function call or response from the size of its JSON encoding.
+func estimateJSONTokens(name string, v map[string]any) int {
+	data, _ := json.Marshal(v)
+	return (len(name) + len(data) + 3) / 4
+}
+
+// Truncator shortens contents that exceed a token budget.
+// The strategies drop whole turns: a user content with the model's answer, including its
+// function calls and their responses.
+type Truncator interface {
+	Truncate(ctx context.Context, contents []*genai.Content, maxTokens int) ([]*genai.Content, error)
+}
+
+var (
+	_ Truncator = DropOldest{}
+	_ Truncator = KeepLast{}
+	_ Truncator = (*Summarize)(nil)
+)
+
+// DropOldest drops the oldest turns until the contents fit. The last turn is always kept.
+type DropOldest struct{}
+
+// Truncate truncates contents.
+func (DropOldest) Truncate(_ context.Context, contents []*genai.Content, maxTokens int) ([]*genai.Content, error) {
+	return dropOldest(turns(contents), maxTokens), nil
+}
+
+// KeepLast keeps the first turns, e.g. with instructions, and the last turns.
+// If they still don't fit, the oldest of the last turns are dropped.
+type KeepLast struct {
+	First, Last int
+}
+
+// Truncate truncates contents.
+func (k KeepLast) Truncate(_ context.Context, contents []*genai.Content, maxTokens int) ([]*genai.Content, error) {
+	first, _, last := split(turns(contents), k.First, k.Last)
+	head := slices.Concat(first...)
+	return append(head, dropOldest(last, maxTokens-EstimateTokens(head))...), nil
+}
+
+// Summarize keeps the first and the last turns and replaces the turns between them with a summary.
+type Summarize struct {
+	// LLM generates the summary.
+	LLM LLM
+	// First and Last are the numbers of kept turns.
+	First, Last int
+	// Prompt is the instruction for the summary. If empty, a default prompt is used.
+	Prompt string
+}
+
+// Truncate truncates contents.
+func (s *Summarize) Truncate(ctx context.Context, contents []*genai.Content, maxTokens int) ([]*genai.Content, error) {
+	first, middle, last := split(turns(contents), s.First, s.Last)
+	if len(middle) == 0 {
+		return DropOldest{}.Truncate(ctx, contents, maxTokens)
+	}
+	prompt := s.Prompt
+	if prompt == "" {
+		prompt = "Summarize the following conversation concisely. Keep all facts, decisions and results that may be needed later."
+	}
+	resp, err := s.LLM.GenerateText(ctx, NewText(prompt+"\n\n"+render(slices.Concat(middle...))), nil)
+	if err != nil {
+		return nil, err
+	}
+	summary := genai.NewContentFromText("Summary of the earlier conversation:\n"+resp.String(), genai.RoleUser)
+	head := slices.Concat(first...)
+	tail := dropOldest(last, maxTokens-EstimateTokens(head)-EstimateTokens([]*genai.Content{summary}))
+	if len(tail) > 0 && tail[0].Role == genai.RoleUser {
+		// The summary is merged into the following user turn, so that the turns still alternate.
+		summary.Parts = append(summary.Parts, tail[0].Parts...)
+		tail = tail[1:]
+	}
+	return slices.Concat(head, []*genai.Content{summary}, tail), nil
+}
+
+// turns groups contents into turns that can be dropped independently.
+// A turn starts with a user content other than function responses and includes the model's
+// answer, with its function calls and their responses, so that the truncated contents
+// start with a user content and alternate between the user and the model.
+func turns(contents []*genai.Content) [][]*genai.Content {
+	var groups [][]*genai.Content
+	start := 0
+	for i := 1; i < len(contents); i++ {
+		if contents[i].Role == genai.RoleUser && !hasFunctionResponses(contents[i]) {
+			groups = append(groups, contents[start:i])
+			start = i
+		}
+	}
+	if len(contents) > 0 {
+		groups = append(groups, contents[start:])
+	}
+	return groups
+}
+
+func split(groups [][]*genai.Content, first, last int) ([][]*genai.Content, [][]*genai.Content, [][]*genai.Content) {
+	first = min(first, len(groups))
+	last = min(last, len(groups)-first)
+	return groups[:first], groups[first : len(groups)-last], groups[len(groups)-last:]
+}
+
+func dropOldest(groups [][]*genai.Content, maxTokens int) []*genai.Content {
+	n := 0
+	for i := len(groups) - 1; i >= 0; i-- {
+		n += EstimateTokens(groups[i])
+		if n > maxTokens && i < len(groups)-1 {
+			return slices.Concat(groups[i+1:]...)
+		}
+	}
+	return slices.Concat(groups...)
+}
+
+func hasFunctionResponses(c *genai.Content) bool {
+	return slices.ContainsFunc(c.Parts, func(p *genai.Part) bool { return p.FunctionResponse != nil })
+}
+
+// render renders contents as a plain text transcript.
+func render(contents []*genai.Content) string {
+	var sb strings.Builder
+	for _, c := range contents {
+		for _, p := range c.Parts {
+			switch {
+			case p.Thought:
+			case p.FunctionCall != nil:
+				args, _ := json.Marshal(p.FunctionCall.Args)
+				fmt.Fprintf(&sb, "%s called %s(%s)\n", c.Role, p.FunctionCall.Name, args)
+			case p.FunctionResponse != nil:
+				resp, _ := json.Marshal(p.FunctionResponse.Response)
+				fmt.Fprintf(&sb, "%s returned %s\n", p.FunctionResponse.Name, resp)
+			case p.InlineData != nil || p.FileData != nil:
+				fmt.Fprintf(&sb, "%s: [attachment]\n", c.Role)
+			case p.Text != "":
+				fmt.Fprintf(&sb, "%s: %s\n", c.Role, p.Text)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// truncate truncates the contents if they exceed the client's input limit.
+func (cl *Client) truncate(ctx context.Context, contents []*genai.Content) ([]*genai.Content, error) {
+	if cl.MaxInputTokens == 0 || EstimateTokens(contents) <= cl.MaxInputTokens {
+		return contents, nil
+	}
+	t := cl.Truncator
+	if t == nil {
+		t = DropOldest{}
+	}
+	return t.Truncate(ctx, contents, cl.MaxInputTokens)
+}
@@ -0,0 +1,202 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+type backendOnly struct {
+	Backend
+}
+
+func history() []*genai.Content {
+	long := strings.Repeat("word ", 40)
+	return []*genai.Content{
+		genai.NewContentFromText("Instructions.", genai.RoleUser),
+		genai.NewContentFromText("OK.", genai.RoleModel),
+		genai.NewContentFromText(long, genai.RoleUser),
+		genai.NewContentFromFunctionCall("lookup", map[string]any{"id": "a"}, genai.RoleModel),
+		genai.NewContentFromFunctionResponse("lookup", map[string]any{"output": long}, genai.RoleUser),
+		genai.NewContentFromText(long, genai.RoleModel),
+		genai.NewContentFromText("Last question?", genai.RoleUser),
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t, `{"totalTokens":42}`)
+	cl := newTestClient(t, s)
+	n, err := cl.CountTokens(context.Background(), NewText("Hello."))
+	req.Nil(err)
+	req.Equal(42, n)
+
+	cl = NewClientWithBackend(backendOnly{cl.backend}, Gemini3FlashPreview)
+	n, err = cl.CountTokens(context.Background(), NewText("Hello, world."))
+	req.Nil(err)
+	req.Equal(4, n)
+	req.Equal(1, len(s.requests))
+}
+
+// requireAlternating checks that the contents start with a user turn and alternate between the user and the model.
+func requireAlternating(t *testing.T, roles ...string) {
+	require.NotEmpty(t, roles)
+	for i, role := range roles {
+		exp := genai.RoleUser
+		if i%2 == 1 {
+			exp = genai.RoleModel
+		}
+		require.Equal(t, exp, role, "content %d", i)
+	}
+}
+
+func roles(contents []*genai.Content) []string {
+	var roles []string
+	for _, c := range contents {
+		roles = append(roles, c.Role)
+	}
+	return roles
+}
+
+func sentRoles(contents []any) []string {
+	var roles []string
+	for _, c := range contents {
+		roles = append(roles, c.(map[string]any)["role"].(string))
+	}
+	return roles
+}
+
+func TestTurns(t *testing.T) {
+	req := require.New(t)
+
+	groups := turns(history())
+	req.Equal(3, len(groups))
+	req.Equal(2, len(groups[0]))
+	// The question is grouped with the function call, its response and the answer.
+	req.Equal(4, len(groups[1]))
+	req.Equal(1, len(groups[2]))
+}
+
+func TestDropOldest(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	h := history()
+	out, err := DropOldest{}.Truncate(ctx, h, 1000)
+	req.Nil(err)
+	req.Equal(h, out)
+
+	out, err = DropOldest{}.Truncate(ctx, h, EstimateTokens(h[2:]))
+	req.Nil(err)
+	req.Equal(h[2:], out)
+	requireAlternating(t, roles(out)...)
+
+	// The question, the function call and its response are dropped together.
+	for _, maxTokens := range []int{EstimateTokens(h[4:]), 60, 1} {
+		out, err = DropOldest{}.Truncate(ctx, h, maxTokens)
+		req.Nil(err)
+		req.Equal(h[6:], out)
+		requireAlternating(t, roles(out)...)
+	}
+}
+
+func TestKeepLast(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	h := history()
+	out, err := KeepLast{First: 2, Last: 3}.Truncate(ctx, h, 1000)
+	req.Nil(err)
+	req.Equal(h, out)
+
+	out, err = KeepLast{First: 1, Last: 1}.Truncate(ctx, h, 1000)
+	req.Nil(err)
+	req.Equal([]*genai.Content{h[0], h[1], h[6]}, out)
+	requireAlternating(t, roles(out)...)
+
+	out, err = KeepLast{First: 1, Last: 2}.Truncate(ctx, h, 20)
+	req.Nil(err)
+	req.Equal([]*genai.Content{h[0], h[1], h[6]}, out)
+	requireAlternating(t, roles(out)...)
+}
+
+func TestSummarize(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t, textResponse("The user asked about a."))
+	cl := newTestClient(t, s)
+
+	h := history()
+	out, err := (&Summarize{LLM: cl, First: 1, Last: 1}).Truncate(context.Background(), h, 1000)
+	req.Nil(err)
+	req.Equal(3, len(out))
+	requireAlternating(t, roles(out)...)
+	req.Equal(h[:2], out[:2])
+	// The summary is merged into the last question.
+	req.Equal(2, len(out[2].Parts))
+	req.Equal("Summary of the earlier conversation:\nThe user asked about a.", out[2].Parts[0].Text)
+	req.Equal("Last question?", out[2].Parts[1].Text)
+	req.Equal(1, len(h[6].Parts))
+
+	prompt := s.requests[0]["contents"].([]any)[0].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"].(string)
+	req.Contains(prompt, `model called lookup({"id":"a"})`)
+	req.NotContains(prompt, "Instructions.")
+}
+
+func TestClientTruncation(t *testing.T) {
+	req := require.New(t)
+
+	s := newFakeServer(t, textResponse("Yes."))
+	cl := newTestClient(t, s)
+	cl.MaxInputTokens = 60
+
+	resp, err := cl.GenerateText(context.Background(), history(), nil)
+	req.Nil(err)
+	req.Equal(2, len(resp.Transcript()))
+	sent := s.requests[0]["contents"].([]any)
+	req.Equal(1, len(sent))
+	requireAlternating(t, sentRoles(sent)...)
+}
+
+func TestTruncationLargeToolOutput(t *testing.T) {
+	req := require.New(t)
+
+	output := map[string]any{"output": strings.Repeat("x", 600_000)}
+	contents := []*genai.Content{
+		genai.NewContentFromText("Fetch the page.", genai.RoleUser),
+		genai.NewContentFromFunctionCall("fetch", map[string]any{"url": "https://example.com"}, genai.RoleModel),
+		genai.NewContentFromFunctionResponse("fetch", output, genai.RoleUser),
+		genai.NewContentFromText("The page is long.", genai.RoleModel),
+		genai.NewContentFromText("Summarize it.", genai.RoleUser),
+	}
+	req.Greater(EstimateTokens(contents[2:3]), 140_000)
+
+	s := newFakeServer(t, textResponse("OK."))
+	cl := newTestClient(t, s)
+	cl.MaxInputTokens = 10_000
+	_, err := cl.GenerateText(context.Background(), contents, nil)
+	req.Nil(err)
+	sent := s.requests[0]["contents"].([]any)
+	req.Equal(1, len(sent))
+	req.Equal("Summarize it.", sent[0].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"])
+
+	// A large output of a tool called in the current turn doesn't drop the question.
+	s = newFakeServer(t, functionCall("lookup", `{"id":"page"}`), textResponse("OK."))
+	cl = newTestClient(t, s)
+	cl.MaxInputTokens = 10_000
+	contents = []*genai.Content{
+		genai.NewContentFromText("Hello.", genai.RoleUser),
+		genai.NewContentFromText("Hi.", genai.RoleModel),
+		genai.NewContentFromText("Fetch the page.", genai.RoleUser),
+	}
+	_, err = cl.GenerateText(context.Background(), contents, []*Tool{lookupTool(t, map[string]string{"page": strings.Repeat("x", 600_000)})})
+	req.Nil(err)
+	sent = s.requests[1]["contents"].([]any)
+	req.Equal(3, len(sent))
+	requireAlternating(t, sentRoles(sent)...)
+	req.Equal("Fetch the page.", sent[0].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"])
+}
@@ -2,6 +2,7 @@ package ai
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/phomola/ai-go/copier"
@@ -47,7 +48,7 @@ func AddFunction[I, O any](tool *Tool, name, description string, f func(context.
 	}
 	tool.Functions[name] = func(ctx context.Context, inMap map[string]any) (map[string]any, error) {
 		if err := inRs.Validate(inMap); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid arguments: %w", err)
 		}
 		in, err := copier.FromMap[I](inMap)
 		if err != nil {
@@ -90,7 +91,7 @@ func (tool *Tool) AddFunction(name, description string, inSchema, outSchema *jso
 	}
 	tool.Functions[name] = func(ctx context.Context, inMap map[string]any) (map[string]any, error) {
 		if err := inRs.Validate(inMap); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid arguments: %w", err)
 		}
 		outMap, err := f(ctx, inMap)
 		if err != nil {
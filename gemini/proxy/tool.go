@@ -24,7 +24,7 @@ type function struct {
 }
 
 // Tool creates a proxy tool.
-func Tool(functions []*infer.Function, emb nlp.Embedding, cl *ai.Client) (*ai.Tool, error) {
+func Tool(functions []*infer.Function, emb nlp.Embedding, llm ai.LLM) (*ai.Tool, error) {
 	funcs := make([]*function, 0, len(functions))
 	for _, f := range functions {
 		vec, err := emb.Vector(f.Description)
@@ -59,7 +59,7 @@ func Tool(functions []*infer.Function, emb nlp.Embedding, cl *ai.Client) (*ai.To
 		if err := tool.AddFunction(fn.Name, fn.Description, fn.InSchema, fn.OutSchema, fn.Fn); err != nil {
 			return nil, err
 		}
-		resp, err := cl.GenerateText(ctx, ai.NewText(in.Prompt), []*ai.Tool{&tool})
+		resp, err := llm.GenerateText(ctx, ai.NewText(in.Prompt), []*ai.Tool{&tool})
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/phomola/ai-go/gemini/ai"
+	"github.com/phomola/ai-go/gemini/ai/aitest"
+	"github.com/phomola/ai-go/infer"
+	"github.com/phomola/ai-go/nlp"
+	"github.com/stretchr/testify/require"
+)
+
+type keywordEmbedding []string
+
+func (e keywordEmbedding) Vector(text string) (nlp.Vector, error) {
+	vec := make(nlp.Vector, len(e))
+	for i, kw := range e {
+		if strings.Contains(strings.ToLower(text), kw) {
+			vec[i] = 1
+		} else {
+			vec[i] = 0.1
+		}
+	}
+	return vec, nil
+}
+
+type forecast struct {
+	Text string `json:"text"`
+}
+
+type weatherService struct{}
+
+func (s *weatherService) GetForecast(_ context.Context, _ *struct {
+	City string `json:"city"`
+}, _ *struct {
+	Info any `guide:"Provides weather forecasts."`
+}) (*forecast, error) {
+	return &forecast{Text: "sunny"}, nil
+}
+
+type quote struct {
+	Price float64 `json:"price"`
+}
+
+type stockService struct{}
+
+func (s *stockService) GetQuote(_ context.Context, _ *struct {
+	Symbol string `json:"symbol"`
+}, _ *struct {
+	Info any `guide:"Provides stock quotes."`
+}) (*quote, error) {
+	return &quote{Price: 1}, nil
+}
+
+func TestTool(t *testing.T) {
+	req := require.New(t)
+
+	weather, err := infer.Functions(new(weatherService))
+	req.Nil(err)
+	stocks, err := infer.Functions(new(stockService))
+	req.Nil(err)
+
+	script := aitest.New(t).
+		Call("proxyTool", map[string]any{"prompt": "What's the weather in Seattle?"}).
+		Call("weatherService:GetForecast", map[string]any{"city": "Seattle"}).
+		ExpectOutput("weatherService:GetForecast", &forecast{Text: "sunny"}).
+		Text("It's sunny.").
+		ExpectOutput("proxyTool", map[string]any{"output": "It's sunny."}).
+		Text("It will be sunny in Seattle.")
+	cl := script.Client()
+
+	tool, err := Tool(append(stocks, weather...), keywordEmbedding{"stock", "weather"}, cl)
+	req.Nil(err)
+
+	resp, err := cl.GenerateText(context.Background(), ai.NewText("What's the weather in Seattle?"), []*ai.Tool{tool})
+	req.Nil(err)
+	req.Equal("It will be sunny in Seattle.", resp.String())
+	reqs := script.Requests()
+	req.Equal(4, len(reqs))
+	decls := reqs[1].Config.Tools[0].FunctionDeclarations
+	req.Equal(1, len(decls))
+	req.Equal("weatherService:GetForecast", decls[0].Name)
+}
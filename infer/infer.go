@@ -17,7 +17,14 @@ var (
 )
 
 // GeminiTool ...
+//
+// Deprecated: Use [Tool] instead.
 func GeminiTool(funcs []*Function) (*ai.Tool, error) {
+	return Tool(funcs)
+}
+
+// Tool ...
+func Tool(funcs []*Function) (*ai.Tool, error) {
 	var tool ai.Tool
 	for _, f := range funcs {
 		if err := tool.AddFunction(f.Name, f.FullDescription(), f.InSchema, f.OutSchema, f.Fn); err != nil {
@@ -105,11 +112,11 @@ func Functions[T any](obj *T) ([]*Function, error) {
 				Guide: field.Tag.Get("jsonschema"),
 			})
 		}
-		inSchema, err := jsonschema.ForType(inType, nil)
+		inSchema, err := ai.SchemaForType(inType)
 		if err != nil {
 			return nil, err
 		}
-		outSchema, err := jsonschema.ForType(outType, nil)
+		outSchema, err := ai.SchemaForType(outType)
 		if err != nil {
 			return nil, err
 		}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/phomola/ai-go/gemini/ai"
+	"github.com/phomola/ai-go/gemini/ai/aitest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -63,4 +65,46 @@ Age: The age.`, funcs[0].FullDescription())
 	tool, err := GeminiTool(funcs)
 	req.Nil(err)
 	req.Equal(1, len(tool.Functions))
+
+	tool, err = Tool(funcs)
+	req.Nil(err)
+	req.Equal(1, len(tool.Functions))
+	req.Equal("tool:Func1", tool.FuncDecls[0].Name)
+}
+
+func TestToolDispatch(t *testing.T) {
+	req := require.New(t)
+
+	funcs, err := Functions(new(tool))
+	req.Nil(err)
+	tool, err := Tool(funcs)
+	req.Nil(err)
+
+	script := aitest.New(t).
+		Call("tool:Func1", map[string]any{"Name": "John", "Age": 20}).
+		ExpectOutput("tool:Func1", &output{Data: "JohnJohn", Num: 40}).
+		Call("tool:Func1", map[string]any{"Name": "", "Age": 1}).
+		ExpectError("tool:Func1", "no name provided").
+		Text("Done.")
+	resp, err := script.Client().GenerateText(context.Background(), ai.NewText("Call it."), []*ai.Tool{tool})
+	req.Nil(err)
+	req.Equal("Done.", resp.String())
+}
+
+type statusService struct{}
+
+func (s *statusService) SetStatus(_ context.Context, in *struct {
+	Status string `json:"status" enum:"open,closed" jsonschema:"The new status."`
+}, _ *struct {
+	Info any `guide:"Sets the status."`
+}) (*output, error) {
+	return &output{Data: in.Status}, nil
+}
+
+func TestFunctionSchemaTags(t *testing.T) {
+	req := require.New(t)
+
+	funcs, err := Functions(new(statusService))
+	req.Nil(err)
+	req.Equal([]any{"open", "closed"}, funcs[0].InSchema.Properties["status"].Enum)
 }
@@ -0,0 +1,53 @@
+// Package backend contains helpers for backends that convert genai contents to other APIs.
+package backend
+
+import (
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Text returns the text of the content's parts, excluding thoughts.
+func Text(c *genai.Content) string {
+	if c == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, p := range c.Parts {
+		if !p.Thought {
+			sb.WriteString(p.Text)
+		}
+	}
+	return sb.String()
+}
+
+// Parameters returns the JSON schema of the function's parameters.
+func Parameters(decl *genai.FunctionDeclaration) any {
+	switch {
+	case decl.ParametersJsonSchema != nil:
+		return decl.ParametersJsonSchema
+	case decl.Parameters != nil:
+		return decl.Parameters
+	default:
+		return map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+}
+
+// CallID returns the ID of a function call, or the function name if the ID is empty.
+func CallID(id, name string) string {
+	if id != "" {
+		return id
+	}
+	return name
+}
+
+// FinishReason maps a finish reason of an API. Unknown non-empty reasons are mapped to [genai.FinishReasonOther].
+func FinishReason(reason string, reasons map[string]genai.FinishReason) genai.FinishReason {
+	if r, ok := reasons[reason]; ok {
+		return r
+	}
+	if reason == "" {
+		return ""
+	}
+	return genai.FinishReasonOther
+}
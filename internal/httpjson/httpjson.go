@@ -0,0 +1,108 @@
+// Package httpjson contains helpers for backends with JSON-over-HTTP APIs.
+package httpjson
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+
+	"github.com/phomola/ai-go/gemini/ai"
+)
+
+// Post sends a JSON request and returns the response body.
+// A non-2xx response is returned as [ai.HTTPError].
+func Post(ctx context.Context, cl *http.Client, url string, header http.Header, in any) (io.ReadCloser, error) {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cl == nil {
+		cl = http.DefaultClient
+	}
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		return nil, &ai.HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     resp.Header,
+			Body:       strings.TrimSpace(string(body)),
+		}
+	}
+	return resp.Body, nil
+}
+
+// Call sends a JSON request and decodes the JSON response.
+func Call(ctx context.Context, cl *http.Client, url string, header http.Header, in, out any) error {
+	body, err := Post(ctx, cl, url, header, in)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return json.NewDecoder(body).Decode(out)
+}
+
+// Events returns the data of server-sent events.
+func Events(r io.Reader) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, 1<<24)
+		var data []byte
+		for sc.Scan() {
+			line := sc.Bytes()
+			if len(line) == 0 {
+				if len(data) > 0 && !yield(data, nil) {
+					return
+				}
+				data = nil
+				continue
+			}
+			if d, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+				if len(data) > 0 {
+					data = append(data, '\n')
+				}
+				data = append(data, bytes.TrimPrefix(d, []byte(" "))...)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			yield(nil, err)
+			return
+		}
+		if len(data) > 0 {
+			yield(data, nil)
+		}
+	}
+}
+
+// Lines returns the non-empty lines of newline-delimited JSON.
+func Lines(r io.Reader) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, 1<<24)
+		for sc.Scan() {
+			if line := bytes.TrimSpace(sc.Bytes()); len(line) > 0 && !yield(line, nil) {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package stub provides a stub HTTP server and a tool shared by the tests of the backends.
+package stub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/phomola/ai-go/gemini/ai"
+	"github.com/stretchr/testify/require"
+)
+
+// Server is a stub HTTP server that responds to JSON requests with prepared responses.
+type Server struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses []string
+	requests  []map[string]any
+}
+
+// NewServer creates a server that responds to the requests for the path with the responses in order.
+// Requests for other paths or without the header fields are rejected. Streamed responses are sent
+// as server-sent events.
+func NewServer(t testing.TB, path string, header http.Header, responses ...string) *Server {
+	s := &Server{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.Error(w, "unexpected path", http.StatusBadRequest)
+			return
+		}
+		for k, v := range header {
+			if r.Header.Get(k) != v[0] {
+				http.Error(w, "unexpected header", http.StatusBadRequest)
+				return
+			}
+		}
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, req)
+		if len(s.responses) == 0 {
+			http.Error(w, "no more responses", http.StatusInternalServerError)
+			return
+		}
+		if req["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.Write([]byte(s.responses[0]))
+		s.responses = s.responses[1:]
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Requests returns the decoded bodies of the requests received so far.
+func (s *Server) Requests() []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]map[string]any(nil), s.requests...)
+}
+
+// LookupInput is the input of the lookup tool.
+type LookupInput struct {
+	ID string `json:"id"`
+}
+
+// LookupOutput is the output of the lookup tool.
+type LookupOutput struct {
+	Value string `json:"value"`
+}
+
+// LookupTool returns a tool with a function 'lookup' that returns the ID in upper case.
+func LookupTool(t testing.TB) *ai.Tool {
+	var tool ai.Tool
+	require.Nil(t, ai.AddFunction(&tool, "lookup", "Looks up a value.", func(_ context.Context, in *LookupInput) (*LookupOutput, error) {
+		return &LookupOutput{Value: strings.ToUpper(in.ID)}, nil
+	}))
+	return &tool
+}
@@ -0,0 +1,60 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/phomola/ai-go/internal/httpjson"
+	"github.com/phomola/ai-go/nlp"
+)
+
+var _ nlp.Embedding = (*Embedding)(nil)
+
+// Embedding is an embedding computed by an Ollama embedding model.
+type Embedding struct {
+	BaseURL    string
+	Model      string
+	HTTPClient *http.Client
+}
+
+// NewEmbedding creates a new embedding.
+func NewEmbedding(baseURL, model string) *Embedding {
+	return &Embedding{BaseURL: baseURL, Model: model}
+}
+
+type (
+	embedRequest struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}
+
+	embedResponse struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+)
+
+// Vector returns the vector for the text.
+func (e *Embedding) Vector(text string) (nlp.Vector, error) {
+	vecs, err := e.Vectors(context.Background(), []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vecs[0], nil
+}
+
+// Vectors returns the vectors for the texts in a single request.
+func (e *Embedding) Vectors(ctx context.Context, texts []string) ([]nlp.Vector, error) {
+	var resp embedResponse
+	if err := httpjson.Call(ctx, e.HTTPClient, baseURL(e.BaseURL)+"/api/embed", nil, &embedRequest{Model: e.Model, Input: texts}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("%d embeddings returned for %d texts", len(resp.Embeddings), len(texts))
+	}
+	vecs := make([]nlp.Vector, len(resp.Embeddings))
+	for i, v := range resp.Embeddings {
+		vecs[i] = v
+	}
+	return vecs, nil
+}
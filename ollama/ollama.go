@@ -0,0 +1,290 @@
+// Package ollama implements a backend and an embedding for the Ollama API.
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"strings"
+
+	"github.com/phomola/ai-go/gemini/ai"
+	"github.com/phomola/ai-go/internal/backend"
+	"github.com/phomola/ai-go/internal/httpjson"
+	"google.golang.org/genai"
+)
+
+var _ ai.Backend = (*Backend)(nil)
+
+// DefaultBaseURL is the base URL of a local Ollama server.
+const DefaultBaseURL = "http://localhost:11434"
+
+// Backend is a backend for the Ollama chat API.
+type Backend struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewBackend creates a new backend.
+func NewBackend(baseURL string) *Backend {
+	return &Backend{BaseURL: baseURL}
+}
+
+// NewClient creates a new client with an Ollama backend.
+func NewClient(baseURL string, model ai.Model, opts ...ai.Option) *ai.Client {
+	return ai.NewClientWithBackend(NewBackend(baseURL), model, opts...)
+}
+
+type (
+	request struct {
+		Model    string     `json:"model"`
+		Messages []*message `json:"messages"`
+		Tools    []*tool    `json:"tools,omitempty"`
+		Format   any        `json:"format,omitempty"`
+		Stream   bool       `json:"stream"`
+		Options  *options   `json:"options,omitempty"`
+	}
+
+	message struct {
+		Role      string      `json:"role"`
+		Content   string      `json:"content"`
+		Thinking  string      `json:"thinking,omitempty"`
+		Images    [][]byte    `json:"images,omitempty"`
+		ToolCalls []*toolCall `json:"tool_calls,omitempty"`
+		ToolName  string      `json:"tool_name,omitempty"`
+	}
+
+	tool struct {
+		Type     string        `json:"type"`
+		Function *toolFunction `json:"function"`
+	}
+
+	toolFunction struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		Parameters  any    `json:"parameters"`
+	}
+
+	toolCall struct {
+		Function *functionCall `json:"function"`
+	}
+
+	functionCall struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	}
+
+	options struct {
+		Temperature *float32 `json:"temperature,omitempty"`
+		TopP        *float32 `json:"top_p,omitempty"`
+		TopK        *float32 `json:"top_k,omitempty"`
+		NumPredict  int32    `json:"num_predict,omitempty"`
+		Stop        []string `json:"stop,omitempty"`
+		Seed        *int32   `json:"seed,omitempty"`
+	}
+
+	response struct {
+		Message         *message `json:"message"`
+		Done            bool     `json:"done"`
+		DoneReason      string   `json:"done_reason"`
+		PromptEvalCount int32    `json:"prompt_eval_count"`
+		EvalCount       int32    `json:"eval_count"`
+		Error           string   `json:"error"`
+	}
+)
+
+// GenerateContent generates a response.
+func (b *Backend) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	req, err := newRequest(model, contents, config)
+	if err != nil {
+		return nil, err
+	}
+	var resp response
+	if err := httpjson.Call(ctx, b.HTTPClient, b.url("/api/chat"), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Message == nil {
+		return nil, fmt.Errorf("no message in response")
+	}
+	return newResponse(fromMessage(resp.Message), &resp), nil
+}
+
+// GenerateContentStream generates a streamed response.
+func (b *Backend) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		req, err := newRequest(model, contents, config)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		req.Stream = true
+		body, err := httpjson.Post(ctx, b.HTTPClient, b.url("/api/chat"), nil, req)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer body.Close()
+		for line, err := range httpjson.Lines(body) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			var chunk response
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				yield(nil, err)
+				return
+			}
+			if chunk.Error != "" {
+				yield(nil, fmt.Errorf("%s", chunk.Error))
+				return
+			}
+			var content *genai.Content
+			if chunk.Message != nil {
+				content = fromMessage(chunk.Message)
+			}
+			if chunk.Done {
+				yield(newResponse(content, &chunk), nil)
+				return
+			}
+			if content != nil && !yield(newResponse(content, nil), nil) {
+				return
+			}
+		}
+	}
+}
+
+func (b *Backend) url(path string) string {
+	return baseURL(b.BaseURL) + path
+}
+
+func baseURL(url string) string {
+	if url == "" {
+		return DefaultBaseURL
+	}
+	return strings.TrimSuffix(url, "/")
+}
+
+func newRequest(model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*request, error) {
+	if config == nil {
+		config = new(genai.GenerateContentConfig)
+	}
+	req := &request{Model: model}
+	if sys := backend.Text(config.SystemInstruction); sys != "" {
+		req.Messages = append(req.Messages, &message{Role: "system", Content: sys})
+	}
+	for _, c := range contents {
+		msgs, err := toMessages(c)
+		if err != nil {
+			return nil, err
+		}
+		req.Messages = append(req.Messages, msgs...)
+	}
+	for _, t := range config.Tools {
+		for _, decl := range t.FunctionDeclarations {
+			req.Tools = append(req.Tools, &tool{
+				Type: "function",
+				Function: &toolFunction{
+					Name:        decl.Name,
+					Description: decl.Description,
+					Parameters:  backend.Parameters(decl),
+				},
+			})
+		}
+	}
+	if config.ResponseMIMEType == "application/json" {
+		switch {
+		case config.ResponseJsonSchema != nil:
+			req.Format = config.ResponseJsonSchema
+		case config.ResponseSchema != nil:
+			req.Format = config.ResponseSchema
+		default:
+			req.Format = "json"
+		}
+	}
+	if config.Temperature != nil || config.TopP != nil || config.TopK != nil || config.MaxOutputTokens != 0 || len(config.StopSequences) > 0 || config.Seed != nil {
+		req.Options = &options{
+			Temperature: config.Temperature,
+			TopP:        config.TopP,
+			TopK:        config.TopK,
+			NumPredict:  config.MaxOutputTokens,
+			Stop:        config.StopSequences,
+			Seed:        config.Seed,
+		}
+	}
+	return req, nil
+}
+
+func toMessages(c *genai.Content) ([]*message, error) {
+	if c.Role == genai.RoleModel {
+		msg := &message{Role: "assistant", Content: backend.Text(c)}
+		for _, p := range c.Parts {
+			if p.FunctionCall != nil {
+				msg.ToolCalls = append(msg.ToolCalls, &toolCall{Function: &functionCall{Name: p.FunctionCall.Name, Arguments: p.FunctionCall.Args}})
+			}
+		}
+		return []*message{msg}, nil
+	}
+	var msgs []*message
+	msg := &message{Role: "user"}
+	for _, p := range c.Parts {
+		switch {
+		case p.FunctionResponse != nil:
+			out, err := json.Marshal(p.FunctionResponse.Response)
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, &message{Role: "tool", Content: string(out), ToolName: p.FunctionResponse.Name})
+		case p.InlineData != nil:
+			if !strings.HasPrefix(p.InlineData.MIMEType, "image/") {
+				return nil, fmt.Errorf("MIME type '%s' not supported", p.InlineData.MIMEType)
+			}
+			msg.Images = append(msg.Images, p.InlineData.Data)
+		case p.FileData != nil:
+			return nil, fmt.Errorf("file data not supported")
+		case !p.Thought:
+			msg.Content += p.Text
+		}
+	}
+	if msg.Content != "" || len(msg.Images) > 0 {
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+func fromMessage(msg *message) *genai.Content {
+	var parts []*genai.Part
+	if msg.Thinking != "" {
+		parts = append(parts, &genai.Part{Text: msg.Thinking, Thought: true})
+	}
+	if msg.Content != "" {
+		parts = append(parts, genai.NewPartFromText(msg.Content))
+	}
+	for _, tc := range msg.ToolCalls {
+		parts = append(parts, genai.NewPartFromFunctionCall(tc.Function.Name, tc.Function.Arguments))
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return genai.NewContentFromParts(parts, genai.RoleModel)
+}
+
+func newResponse(content *genai.Content, final *response) *genai.GenerateContentResponse {
+	cand := &genai.Candidate{Content: content}
+	resp := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{cand}}
+	if final != nil {
+		cand.FinishReason = backend.FinishReason(final.DoneReason, doneReasons)
+		resp.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     final.PromptEvalCount,
+			CandidatesTokenCount: final.EvalCount,
+			TotalTokenCount:      final.PromptEvalCount + final.EvalCount,
+		}
+	}
+	return resp
+}
+
+var doneReasons = map[string]genai.FinishReason{
+	"":       genai.FinishReasonStop,
+	"stop":   genai.FinishReasonStop,
+	"length": genai.FinishReasonMaxTokens,
+}
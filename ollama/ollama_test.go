@@ -0,0 +1,98 @@
+package ollama
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/phomola/ai-go/gemini/ai"
+	"github.com/phomola/ai-go/internal/stub"
+	"github.com/phomola/ai-go/nlp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolCalling(t *testing.T) {
+	req := require.New(t)
+
+	s := stub.NewServer(t, "/api/chat", nil,
+		`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"lookup","arguments":{"id":"a"}}}]},"done":true,"done_reason":"stop"}`,
+		`{"message":{"role":"assistant","content":"It is A."},"done":true,"done_reason":"stop","prompt_eval_count":30,"eval_count":4}`,
+	)
+	cl := NewClient(s.URL, "llama3.2")
+
+	resp, err := cl.GenerateText(context.Background(), ai.NewText("What is a?"), []*ai.Tool{stub.LookupTool(t)})
+	req.Nil(err)
+	req.Equal("It is A.", resp.String())
+
+	reqs := s.Requests()
+	req.Equal(2, len(reqs))
+	req.Equal(false, reqs[0]["stream"])
+	req.Equal("lookup", reqs[0]["tools"].([]any)[0].(map[string]any)["function"].(map[string]any)["name"])
+	msgs := reqs[1]["messages"].([]any)
+	req.Equal(3, len(msgs))
+	req.Equal(map[string]any{"role": "tool", "content": `{"output":{"value":"A"}}`, "tool_name": "lookup"}, msgs[2])
+}
+
+func TestStructuredOutput(t *testing.T) {
+	req := require.New(t)
+
+	s := stub.NewServer(t, "/api/chat", nil,
+		`{"message":{"role":"assistant","content":"{\"value\":\"x\"}"},"done":true}`,
+	)
+	cl := NewClient(s.URL, "llama3.2")
+
+	out, err := cl.Generate[stub.LookupOutput](context.Background(), ai.NewTextWithBytes("Describe.", []byte("img"), ai.MimeTypeImageJPEG), nil)
+	req.Nil(err)
+	req.Equal("x", out.Value)
+
+	r := s.Requests()[0]
+	req.Equal("object", r["format"].(map[string]any)["type"])
+	req.Equal([]any{"aW1n"}, r["messages"].([]any)[0].(map[string]any)["images"])
+}
+
+func TestStreaming(t *testing.T) {
+	req := require.New(t)
+
+	s := stub.NewServer(t, "/api/chat", nil,
+		`{"message":{"role":"assistant","content":"Hello"},"done":false}
+{"message":{"role":"assistant","content":", world."},"done":false}
+{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":10,"eval_count":3}
+`,
+	)
+	cl := NewClient(s.URL, "llama3.2", ai.WithSystemInstruction("Be brief."))
+
+	var (
+		text  strings.Builder
+		total int32
+	)
+	for chunk, err := range cl.GenerateTextStream(context.Background(), ai.NewText("Say hello."), nil) {
+		req.Nil(err)
+		text.WriteString(chunk.Text)
+		if chunk.Usage != nil {
+			total = chunk.Usage.TotalTokenCount
+		}
+	}
+	req.Equal("Hello, world.", text.String())
+	req.Equal(int32(13), total)
+	req.Equal(map[string]any{"role": "system", "content": "Be brief."}, s.Requests()[0]["messages"].([]any)[0])
+}
+
+func TestEmbedding(t *testing.T) {
+	req := require.New(t)
+
+	s := stub.NewServer(t, "/api/embed", nil,
+		`{"embeddings":[[3,4]]}`,
+		`{"embeddings":[[1,0],[0,1]]}`,
+	)
+	emb := NewEmbedding(s.URL, "nomic-embed-text")
+
+	vec, err := emb.Vector("hello")
+	req.Nil(err)
+	req.Equal(nlp.Vector{3, 4}, vec)
+	req.Equal(5.0, vec.Length())
+
+	vecs, err := emb.Vectors(context.Background(), []string{"a", "b"})
+	req.Nil(err)
+	req.Equal([]nlp.Vector{{1, 0}, {0, 1}}, vecs)
+	req.Equal(map[string]any{"model": "nomic-embed-text", "input": []any{"a", "b"}}, s.Requests()[1])
+}
@@ -0,0 +1,420 @@
+// Package openai implements a backend for the OpenAI Chat Completions API
+// and compatible servers such as vLLM and llama.cpp.
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"strings"
+
+	"github.com/phomola/ai-go/gemini/ai"
+	"github.com/phomola/ai-go/internal/backend"
+	"github.com/phomola/ai-go/internal/httpjson"
+	"google.golang.org/genai"
+)
+
+var _ ai.Backend = (*Backend)(nil)
+
+// DefaultBaseURL is the base URL of the OpenAI API.
+const DefaultBaseURL = "https://api.openai.com/v1"
+
+// Backend is a backend for the Chat Completions API.
+type Backend struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// NewBackend creates a new backend. The API key is optional for local servers.
+func NewBackend(baseURL, apiKey string) *Backend {
+	return &Backend{BaseURL: baseURL, APIKey: apiKey}
+}
+
+// NewClient creates a new client with a Chat Completions backend.
+func NewClient(baseURL, apiKey string, model ai.Model, opts ...ai.Option) *ai.Client {
+	return ai.NewClientWithBackend(NewBackend(baseURL, apiKey), model, opts...)
+}
+
+type (
+	request struct {
+		Model          string          `json:"model"`
+		Messages       []*message      `json:"messages"`
+		Tools          []*tool         `json:"tools,omitempty"`
+		ResponseFormat *responseFormat `json:"response_format,omitempty"`
+		Temperature    *float32        `json:"temperature,omitempty"`
+		TopP           *float32        `json:"top_p,omitempty"`
+		MaxTokens      int32           `json:"max_tokens,omitempty"`
+		Stop           []string        `json:"stop,omitempty"`
+		Seed           *int32          `json:"seed,omitempty"`
+		Stream         bool            `json:"stream,omitempty"`
+		StreamOptions  *streamOptions  `json:"stream_options,omitempty"`
+	}
+
+	message struct {
+		Role       string      `json:"role"`
+		Content    any         `json:"content,omitempty"`
+		ToolCalls  []*toolCall `json:"tool_calls,omitempty"`
+		ToolCallID string      `json:"tool_call_id,omitempty"`
+	}
+
+	contentPart struct {
+		Type     string    `json:"type"`
+		Text     string    `json:"text,omitempty"`
+		ImageURL *imageURL `json:"image_url,omitempty"`
+		File     *file     `json:"file,omitempty"`
+	}
+
+	imageURL struct {
+		URL string `json:"url"`
+	}
+
+	file struct {
+		FileName string `json:"filename,omitempty"`
+		FileData string `json:"file_data"`
+	}
+
+	tool struct {
+		Type     string        `json:"type"`
+		Function *toolFunction `json:"function"`
+	}
+
+	toolFunction struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+		Parameters  any    `json:"parameters"`
+	}
+
+	toolCall struct {
+		Index    *int          `json:"index,omitempty"`
+		ID       string        `json:"id,omitempty"`
+		Type     string        `json:"type,omitempty"`
+		Function *functionCall `json:"function"`
+	}
+
+	functionCall struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	}
+
+	responseFormat struct {
+		Type       string      `json:"type"`
+		JSONSchema *jsonSchema `json:"json_schema,omitempty"`
+	}
+
+	jsonSchema struct {
+		Name   string `json:"name"`
+		Schema any    `json:"schema"`
+	}
+
+	streamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	}
+
+	response struct {
+		Choices []*choice `json:"choices"`
+		Usage   *usage    `json:"usage"`
+	}
+
+	choice struct {
+		Message      *responseMessage `json:"message"`
+		Delta        *responseMessage `json:"delta"`
+		FinishReason string           `json:"finish_reason"`
+	}
+
+	responseMessage struct {
+		Content   string      `json:"content"`
+		ToolCalls []*toolCall `json:"tool_calls"`
+	}
+
+	usage struct {
+		PromptTokens     int32 `json:"prompt_tokens"`
+		CompletionTokens int32 `json:"completion_tokens"`
+		TotalTokens      int32 `json:"total_tokens"`
+	}
+)
+
+// GenerateContent generates a response.
+func (b *Backend) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	req, err := newRequest(model, contents, config)
+	if err != nil {
+		return nil, err
+	}
+	var resp response
+	if err := httpjson.Call(ctx, b.HTTPClient, b.url(), b.header(), req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message == nil {
+		return nil, fmt.Errorf("no choices in response")
+	}
+	ch := resp.Choices[0]
+	content, err := fromMessage(ch.Message.Content, ch.Message.ToolCalls)
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(content, ch.FinishReason, resp.Usage), nil
+}
+
+// GenerateContentStream generates a streamed response.
+func (b *Backend) GenerateContentStream(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) iter.Seq2[*genai.GenerateContentResponse, error] {
+	return func(yield func(*genai.GenerateContentResponse, error) bool) {
+		req, err := newRequest(model, contents, config)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		req.Stream = true
+		req.StreamOptions = &streamOptions{IncludeUsage: true}
+		body, err := httpjson.Post(ctx, b.HTTPClient, b.url(), b.header(), req)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer body.Close()
+		var (
+			calls        []*toolCall
+			finishReason string
+			u            *usage
+		)
+		for data, err := range httpjson.Events(body) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if string(data) == "[DONE]" {
+				break
+			}
+			var chunk response
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				yield(nil, err)
+				return
+			}
+			if chunk.Usage != nil {
+				u = chunk.Usage
+			}
+			if len(chunk.Choices) == 0 || chunk.Choices[0].Delta == nil {
+				continue
+			}
+			ch := chunk.Choices[0]
+			if ch.FinishReason != "" {
+				finishReason = ch.FinishReason
+			}
+			for _, d := range ch.Delta.ToolCalls {
+				i := len(calls) - 1
+				switch {
+				case d.Index != nil:
+					i = *d.Index
+				case d.ID != "" || len(calls) == 0:
+					// Some servers omit the index, a call with an ID starts a new call.
+					i = len(calls)
+				}
+				for i >= len(calls) {
+					calls = append(calls, &toolCall{Function: new(functionCall)})
+				}
+				if d.ID != "" {
+					calls[i].ID = d.ID
+				}
+				if d.Function != nil {
+					calls[i].Function.Name += d.Function.Name
+					calls[i].Function.Arguments += d.Function.Arguments
+				}
+			}
+			if ch.Delta.Content != "" {
+				content := genai.NewContentFromText(ch.Delta.Content, genai.RoleModel)
+				if !yield(newResponse(content, "", nil), nil) {
+					return
+				}
+			}
+		}
+		var content *genai.Content
+		if len(calls) > 0 {
+			if content, err = fromMessage("", calls); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+		yield(newResponse(content, finishReason, u), nil)
+	}
+}
+
+func (b *Backend) url() string {
+	baseURL := b.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/chat/completions"
+}
+
+func (b *Backend) header() http.Header {
+	h := make(http.Header)
+	if b.APIKey != "" {
+		h.Set("Authorization", "Bearer "+b.APIKey)
+	}
+	return h
+}
+
+func newRequest(model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*request, error) {
+	req := &request{Model: model}
+	if config == nil {
+		config = new(genai.GenerateContentConfig)
+	}
+	if sys := backend.Text(config.SystemInstruction); sys != "" {
+		req.Messages = append(req.Messages, &message{Role: "system", Content: sys})
+	}
+	for _, c := range contents {
+		msgs, err := toMessages(c)
+		if err != nil {
+			return nil, err
+		}
+		req.Messages = append(req.Messages, msgs...)
+	}
+	for _, t := range config.Tools {
+		for _, decl := range t.FunctionDeclarations {
+			req.Tools = append(req.Tools, &tool{
+				Type: "function",
+				Function: &toolFunction{
+					Name:        decl.Name,
+					Description: decl.Description,
+					Parameters:  backend.Parameters(decl),
+				},
+			})
+		}
+	}
+	if config.ResponseMIMEType == "application/json" {
+		switch {
+		case config.ResponseJsonSchema != nil:
+			req.ResponseFormat = &responseFormat{Type: "json_schema", JSONSchema: &jsonSchema{Name: "response", Schema: config.ResponseJsonSchema}}
+		case config.ResponseSchema != nil:
+			req.ResponseFormat = &responseFormat{Type: "json_schema", JSONSchema: &jsonSchema{Name: "response", Schema: config.ResponseSchema}}
+		default:
+			req.ResponseFormat = &responseFormat{Type: "json_object"}
+		}
+	}
+	req.Temperature = config.Temperature
+	req.TopP = config.TopP
+	req.MaxTokens = config.MaxOutputTokens
+	req.Stop = config.StopSequences
+	req.Seed = config.Seed
+	return req, nil
+}
+
+func toMessages(c *genai.Content) ([]*message, error) {
+	if c.Role == genai.RoleModel {
+		msg := &message{Role: "assistant"}
+		if t := backend.Text(c); t != "" {
+			msg.Content = t
+		}
+		for _, p := range c.Parts {
+			if p.FunctionCall == nil {
+				continue
+			}
+			args, err := json.Marshal(p.FunctionCall.Args)
+			if err != nil {
+				return nil, err
+			}
+			msg.ToolCalls = append(msg.ToolCalls, &toolCall{
+				ID:       backend.CallID(p.FunctionCall.ID, p.FunctionCall.Name),
+				Type:     "function",
+				Function: &functionCall{Name: p.FunctionCall.Name, Arguments: string(args)},
+			})
+		}
+		return []*message{msg}, nil
+	}
+	var (
+		msgs  []*message
+		parts []*contentPart
+	)
+	for _, p := range c.Parts {
+		switch {
+		case p.FunctionResponse != nil:
+			out, err := json.Marshal(p.FunctionResponse.Response)
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, &message{
+				Role:       "tool",
+				Content:    string(out),
+				ToolCallID: backend.CallID(p.FunctionResponse.ID, p.FunctionResponse.Name),
+			})
+		case p.InlineData != nil:
+			part, err := dataPart(p.InlineData.MIMEType, "data:"+p.InlineData.MIMEType+";base64,"+base64.StdEncoding.EncodeToString(p.InlineData.Data), p.InlineData.DisplayName)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		case p.FileData != nil:
+			part, err := dataPart(p.FileData.MIMEType, p.FileData.FileURI, p.FileData.DisplayName)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		case p.Text != "" && !p.Thought:
+			parts = append(parts, &contentPart{Type: "text", Text: p.Text})
+		}
+	}
+	switch {
+	case len(parts) == 1 && parts[0].Type == "text":
+		msgs = append(msgs, &message{Role: "user", Content: parts[0].Text})
+	case len(parts) > 0:
+		msgs = append(msgs, &message{Role: "user", Content: parts})
+	}
+	return msgs, nil
+}
+
+func dataPart(mimeType, url, name string) (*contentPart, error) {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return &contentPart{Type: "image_url", ImageURL: &imageURL{URL: url}}, nil
+	case mimeType == ai.MimeTypePDF:
+		return &contentPart{Type: "file", File: &file{FileName: name, FileData: url}}, nil
+	default:
+		return nil, fmt.Errorf("MIME type '%s' not supported", mimeType)
+	}
+}
+
+func fromMessage(text string, calls []*toolCall) (*genai.Content, error) {
+	var parts []*genai.Part
+	if text != "" {
+		parts = append(parts, genai.NewPartFromText(text))
+	}
+	for _, tc := range calls {
+		var args map[string]any
+		if tc.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+				return nil, fmt.Errorf("arguments of tool call '%s' ill-formed: %w", tc.Function.Name, err)
+			}
+		}
+		part := genai.NewPartFromFunctionCall(tc.Function.Name, args)
+		part.FunctionCall.ID = tc.ID
+		parts = append(parts, part)
+	}
+	return genai.NewContentFromParts(parts, genai.RoleModel), nil
+}
+
+func newResponse(content *genai.Content, finishReason string, u *usage) *genai.GenerateContentResponse {
+	resp := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			Content:      content,
+			FinishReason: backend.FinishReason(finishReason, finishReasons),
+		}},
+	}
+	if u != nil {
+		resp.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     u.PromptTokens,
+			CandidatesTokenCount: u.CompletionTokens,
+			TotalTokenCount:      u.TotalTokens,
+		}
+	}
+	return resp
+}
+
+var finishReasons = map[string]genai.FinishReason{
+	"stop":           genai.FinishReasonStop,
+	"tool_calls":     genai.FinishReasonStop,
+	"function_call":  genai.FinishReasonStop,
+	"length":         genai.FinishReasonMaxTokens,
+	"content_filter": genai.FinishReasonSafety,
+}
@@ -0,0 +1,163 @@
+package openai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/phomola/ai-go/gemini/ai"
+	"github.com/phomola/ai-go/internal/stub"
+	"github.com/stretchr/testify/require"
+)
+
+func newStubServer(t *testing.T, responses ...string) *stub.Server {
+	return stub.NewServer(t, "/v1/chat/completions", http.Header{"Authorization": {"Bearer key"}}, responses...)
+}
+
+func TestToolCalling(t *testing.T) {
+	req := require.New(t)
+
+	s := newStubServer(t,
+		`{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"id\":\"a\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		`{"choices":[{"message":{"role":"assistant","content":"It is A."},"finish_reason":"stop"}],"usage":{"prompt_tokens":20,"completion_tokens":3,"total_tokens":23}}`,
+	)
+	cl := NewClient(s.URL+"/v1", "key", "local-model")
+
+	resp, err := cl.GenerateText(context.Background(), ai.NewText("What is a?"), []*ai.Tool{stub.LookupTool(t)})
+	req.Nil(err)
+	req.Equal("It is A.", resp.String())
+	req.Equal(2, len(s.Requests()))
+
+	tools := s.Requests()[0]["tools"].([]any)
+	req.Equal(1, len(tools))
+	fn := tools[0].(map[string]any)["function"].(map[string]any)
+	req.Equal("lookup", fn["name"])
+	req.Equal("object", fn["parameters"].(map[string]any)["type"])
+
+	msgs := s.Requests()[1]["messages"].([]any)
+	req.Equal(3, len(msgs))
+	req.Equal(map[string]any{"role": "user", "content": "What is a?"}, msgs[0])
+	req.Equal("call_1", msgs[1].(map[string]any)["tool_calls"].([]any)[0].(map[string]any)["id"])
+	req.Equal(map[string]any{"role": "tool", "tool_call_id": "call_1", "content": `{"output":{"value":"A"}}`}, msgs[2])
+}
+
+func TestStructuredOutput(t *testing.T) {
+	req := require.New(t)
+
+	s := newStubServer(t,
+		`{"choices":[{"message":{"role":"assistant","content":"{\"value\":\"x\"}"},"finish_reason":"stop"}]}`,
+	)
+	cl := NewClient(s.URL+"/v1", "key", "local-model")
+
+	out, err := cl.Generate[stub.LookupOutput](context.Background(), ai.NewTextWithBytes("Describe.", []byte{0x89, 'P', 'N', 'G'}, ai.MimeTypeImagePNG), nil)
+	req.Nil(err)
+	req.Equal("x", out.Value)
+
+	format := s.Requests()[0]["response_format"].(map[string]any)
+	req.Equal("json_schema", format["type"])
+	req.Equal("object", format["json_schema"].(map[string]any)["schema"].(map[string]any)["type"])
+	content := s.Requests()[0]["messages"].([]any)[0].(map[string]any)["content"].([]any)
+	req.Equal(2, len(content))
+	req.Equal("image_url", content[0].(map[string]any)["type"])
+	req.Equal("data:image/png;base64,iVBORw==", content[0].(map[string]any)["image_url"].(map[string]any)["url"])
+}
+
+func TestStreaming(t *testing.T) {
+	req := require.New(t)
+
+	s := newStubServer(t,
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup","arguments":""}}]}}]}
+
+data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"id\":"}}]}}]}
+
+data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"b\"}"}}]},"finish_reason":"tool_calls"}]}
+
+data: [DONE]
+
+`,
+		`data: {"choices":[{"delta":{"content":"It "}}]}
+
+data: {"choices":[{"delta":{"content":"is B."},"finish_reason":"stop"}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":30,"completion_tokens":4,"total_tokens":34}}
+
+data: [DONE]
+
+`,
+	)
+	cl := NewClient(s.URL+"/v1", "key", "local-model", ai.WithSystemInstruction("Be brief."))
+
+	var (
+		text  strings.Builder
+		calls int
+		total int32
+	)
+	for chunk, err := range cl.GenerateTextStream(context.Background(), ai.NewText("What is b?"), []*ai.Tool{stub.LookupTool(t)}) {
+		req.Nil(err)
+		text.WriteString(chunk.Text)
+		if chunk.FunctionCall != nil {
+			calls++
+			req.Equal(map[string]any{"id": "b"}, chunk.FunctionCall.Args)
+		}
+		if chunk.Usage != nil {
+			total = chunk.Usage.TotalTokenCount
+		}
+	}
+	req.Equal("It is B.", text.String())
+	req.Equal(1, calls)
+	req.Equal(int32(34), total)
+	req.Equal(true, s.Requests()[0]["stream"])
+	req.Equal(map[string]any{"role": "system", "content": "Be brief."}, s.Requests()[0]["messages"].([]any)[0])
+}
+
+func TestStreamingWithoutIndex(t *testing.T) {
+	req := require.New(t)
+
+	s := newStubServer(t,
+		`data: {"choices":[{"delta":{"tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"id\":"}}]}}]}
+
+data: {"choices":[{"delta":{"tool_calls":[{"function":{"arguments":"\"a\"}"}}]}}]}
+
+data: {"choices":[{"delta":{"tool_calls":[{"id":"call_2","type":"function","function":{"name":"lookup","arguments":"{\"id\":\"b\"}"}}]},"finish_reason":"tool_calls"}]}
+
+data: [DONE]
+
+`,
+		`data: {"choices":[{"delta":{"content":"A and B."},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`,
+	)
+	cl := NewClient(s.URL+"/v1", "key", "local-model")
+
+	var (
+		text strings.Builder
+		args []map[string]any
+	)
+	for chunk, err := range cl.GenerateTextStream(context.Background(), ai.NewText("What are a and b?"), []*ai.Tool{stub.LookupTool(t)}) {
+		req.Nil(err)
+		text.WriteString(chunk.Text)
+		if chunk.FunctionCall != nil {
+			args = append(args, chunk.FunctionCall.Args)
+		}
+	}
+	req.Equal("A and B.", text.String())
+	req.Equal([]map[string]any{{"id": "a"}, {"id": "b"}}, args)
+	calls := s.Requests()[1]["messages"].([]any)[1].(map[string]any)["tool_calls"].([]any)
+	req.Equal("call_2", calls[1].(map[string]any)["id"])
+}
+
+func TestHTTPError(t *testing.T) {
+	req := require.New(t)
+
+	s := newStubServer(t)
+	cl := NewClient(s.URL+"/v1", "key", "local-model")
+
+	_, err := cl.GenerateText(context.Background(), ai.NewText("Hi."), nil)
+	var httpErr *ai.HTTPError
+	req.ErrorAs(err, &httpErr)
+	req.Equal(http.StatusInternalServerError, httpErr.StatusCode)
+	req.Equal("no more responses", httpErr.Body)
+}